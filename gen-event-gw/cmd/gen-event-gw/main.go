@@ -1,12 +1,35 @@
 package main
 
 import (
+	"flag"
+	"os"
+
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/config"
 	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/serve"
 	log "github.com/sirupsen/logrus"
 )
 
 func main() {
-	err := serve.NewRouter()
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		err := replay(os.Args[2:])
+		if err == flag.ErrHelp {
+			os.Exit(0)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	cfg, err := config.Load(os.Args[1:])
+	if err == flag.ErrHelp {
+		os.Exit(0)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = serve.NewRouter(cfg)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/capture"
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/config"
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/events"
+	log "github.com/sirupsen/logrus"
+)
+
+//replay - runs the captured requests through the current config
+//The resulting events are printed as NDJSON, they are only forwarded with -forward
+func replay(args []string) error {
+	fs := flag.NewFlagSet("gen-event-gw replay", flag.ContinueOnError)
+	archive := fs.String("archive", "", "Capture file or directory to replay, defaults to the capture directory")
+	forward := fs.Bool("forward", false, "Forward the replayed events, otherwise they are only printed (dry-run)")
+	onlyApp := fs.String("only-app", "", "Only replay the requests of this app")
+
+	cfg, err := config.LoadFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	path := *archive
+	if path == "" {
+		path = cfg.Capture.Dir
+	}
+	if path == "" {
+		return fmt.Errorf("Invalid configuration - Missing the capture archive to replay")
+	}
+
+	processers := make(map[string]*events.KymaEventProcesser, len(cfg.Apps))
+	for _, app := range cfg.Apps {
+		//replayed events must not be suppressed as duplicates or end up in the quarantine
+		app.Dedup.Enabled = false
+		app.Schema.Action = config.SchemaActionReject
+
+		k, err := events.NewKymaEventProcesser(&app, nil, nil)
+		if err != nil {
+			return err
+		}
+		processers[app.Name] = k
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	replayed, failed := 0, 0
+	err = capture.Read(path, func(record *capture.Record) error {
+		if *onlyApp != "" && record.App != *onlyApp {
+			return nil
+		}
+
+		k, ok := processers[record.App]
+		if !ok {
+			failed++
+			return encoder.Encode(&events.ReplayResult{Time: record.Time, App: record.App, Error: "app is not configured"})
+		}
+
+		for _, result := range k.Replay(record, *forward) {
+			replayed++
+			if result.Error != "" {
+				failed++
+			}
+			if err := encoder.Encode(result); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Replayed %d events, %d failed", replayed, failed)
+	return nil
+}
@@ -5,6 +5,11 @@ go 1.13
 require (
 	github.com/gofrs/uuid v3.2.0+incompatible
 	github.com/gorilla/mux v1.7.3
+	github.com/prometheus/client_golang v1.0.0
 	github.com/sirupsen/logrus v1.4.2
 	github.com/tidwall/gjson v1.4.0
+	github.com/tidwall/sjson v1.0.4
+	github.com/xeipuuv/gojsonschema v1.2.0
+	golang.org/x/time v0.0.0-20190921001708-c4c64cad1fd0
+	gopkg.in/yaml.v2 v2.2.4
 )
@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	//ModeNone - every request is accepted
+	ModeNone = "none"
+	//ModeBasic - static basic auth user
+	ModeBasic = "basic"
+	//ModeHMAC - signature of the request body
+	ModeHMAC = "hmac"
+	//ModeBearer - static bearer tokens
+	ModeBearer = "bearer"
+	//ModeAPIKey - static api keys in a header or query parameter
+	ModeAPIKey = "apikey"
+	//ModeJWT - JWT bearer tokens validated against a JWKS file
+	ModeJWT = "jwt"
+	//ModeMTLS - verified client certificates
+	ModeMTLS = "mtls"
+
+	anonymous = "anonymous"
+)
+
+type contextKey int
+
+const principalKey contextKey = iota
+
+//Authenticator - verifies the origin of an inbound request
+type Authenticator interface {
+	//Authenticate returns the authenticated principal, the body is passed for signature validation
+	Authenticate(r *http.Request, body []byte) (string, error)
+}
+
+//Config - settings of all authenticators, only the settings of the selected mode are used
+type Config struct {
+	Mode string `yaml:"mode"`
+
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+
+	HMAC HMACConfig `yaml:"hmac"`
+
+	BearerTokens []string `yaml:"bearerTokens"`
+
+	APIKeys      []string `yaml:"apiKeys"`
+	APIKeyHeader string   `yaml:"apiKeyHeader"`
+	APIKeyQuery  string   `yaml:"apiKeyQuery"`
+
+	JWKSFile    string `yaml:"jwksFile"`
+	JWTIssuer   string `yaml:"jwtIssuer"`
+	JWTAudience string `yaml:"jwtAudience"`
+
+	MTLSAllowedSubjects []string `yaml:"mtlsAllowedSubjects"`
+}
+
+//HMACConfig - settings of the HMAC authenticator
+type HMACConfig struct {
+	Scheme          string        `yaml:"scheme"`
+	Secret          string        `yaml:"secret"`
+	Header          string        `yaml:"header"`
+	Algorithm       string        `yaml:"algorithm"`
+	Encoding        string        `yaml:"encoding"`
+	Prefix          string        `yaml:"prefix"`
+	TimestampHeader string        `yaml:"timestampHeader"`
+	Tolerance       time.Duration `yaml:"tolerance"`
+}
+
+//New - creates the authenticator of the configured mode
+func New(config Config) (Authenticator, error) {
+	switch config.Mode {
+	case ModeNone:
+		return &none{}, nil
+	case ModeBasic:
+		return newBasic(config.Username, config.Password)
+	case ModeHMAC:
+		return newHMAC(config.HMAC)
+	case ModeBearer:
+		return newBearer(config.BearerTokens)
+	case ModeAPIKey:
+		return newAPIKey(config.APIKeys, config.APIKeyHeader, config.APIKeyQuery)
+	case ModeJWT:
+		return newJWT(config.JWKSFile, config.JWTIssuer, config.JWTAudience)
+	case ModeMTLS:
+		return &mtls{allowedSubjects: toSet(config.MTLSAllowedSubjects)}, nil
+	default:
+		return nil, fmt.Errorf("unknown authentication mode %q", config.Mode)
+	}
+}
+
+//Middleware - rejects requests the authenticator does not accept with 401
+func Middleware(authenticator Authenticator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "Could not read data", http.StatusBadRequest)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		principal, err := authenticator.Authenticate(r, body)
+		if err != nil {
+			log.Printf("Unauthorized request has been attempted - %s", err.Error())
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey, principal)))
+	})
+}
+
+//Principal - the principal authenticated by the middleware, empty if the request did not pass it
+func Principal(r *http.Request) string {
+	principal, _ := r.Context().Value(principalKey).(string)
+	return principal
+}
+
+type none struct{}
+
+func (n *none) Authenticate(r *http.Request, body []byte) (string, error) {
+	return anonymous, nil
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range nonEmpty(values) {
+		set[value] = true
+	}
+	return set
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+
+	invalid := []Config{
+		{Mode: "unknown"},
+		{Mode: ModeBasic, Username: "user"},
+		{Mode: ModeHMAC},
+		{Mode: ModeBearer, BearerTokens: []string{" "}},
+		{Mode: ModeAPIKey, APIKeys: []string{"key"}},
+		{Mode: ModeJWT},
+	}
+
+	for _, config := range invalid {
+		if _, err := New(config); err == nil {
+			t.Errorf("config %+v should be rejected", config)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	authenticator, _ := New(Config{Mode: ModeBasic, Username: "user", Password: "secret"})
+
+	var receivedBody, receivedPrincipal string
+	handler := Middleware(authenticator, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		receivedBody = string(body)
+		receivedPrincipal = Principal(r)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(`{"a":1}`))
+	req.SetBasicAuth("user", "secret")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK || receivedBody != `{"a":1}` || receivedPrincipal != "user" {
+		t.Errorf("authenticated request not passed correctly: %d %q %q", rr.Code, receivedBody, receivedPrincipal)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(`{"a":1}`))
+	req.SetBasicAuth("user", "wrong")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("status code 401 expected, %d received", rr.Code)
+	}
+}
+
+func TestBearer(t *testing.T) {
+	authenticator, _ := New(Config{Mode: ModeBearer, BearerTokens: []string{"token-a", "token-b"}})
+
+	req := httptest.NewRequest(http.MethodPost, "/events", nil)
+	req.Header.Set("Authorization", "Bearer token-b")
+	if principal, err := authenticator.Authenticate(req, nil); err != nil || principal != "bearer-1" {
+		t.Errorf("valid token rejected: %v", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer token-c")
+	if _, err := authenticator.Authenticate(req, nil); err == nil {
+		t.Error("invalid token accepted")
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	authenticator, _ := New(Config{Mode: ModeAPIKey, APIKeys: []string{"key"}, APIKeyHeader: "X-API-Key", APIKeyQuery: "apikey"})
+
+	req := httptest.NewRequest(http.MethodPost, "/events", nil)
+	req.Header.Set("X-API-Key", "key")
+	if _, err := authenticator.Authenticate(req, nil); err != nil {
+		t.Errorf("valid header key rejected: %s", err.Error())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/events?apikey=key", nil)
+	if _, err := authenticator.Authenticate(req, nil); err != nil {
+		t.Errorf("valid query key rejected: %s", err.Error())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/events?apikey=other", nil)
+	if _, err := authenticator.Authenticate(req, nil); err == nil {
+		t.Error("invalid key accepted")
+	}
+}
+
+func TestMTLS(t *testing.T) {
+	authenticator, _ := New(Config{Mode: ModeMTLS})
+
+	req := httptest.NewRequest(http.MethodPost, "/events", nil)
+	if _, err := authenticator.Authenticate(req, nil); err == nil {
+		t.Error("request without client certificate accepted")
+	}
+}
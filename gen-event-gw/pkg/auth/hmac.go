@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	//SchemeGeneric - header, algorithm, encoding and prefix as configured
+	SchemeGeneric = "generic"
+	//SchemeGitHub - X-Hub-Signature-256: sha256=<hex>
+	SchemeGitHub = "github"
+	//SchemeShopify - X-Shopify-Hmac-Sha256: <base64>
+	SchemeShopify = "shopify"
+	//SchemeStripe - Stripe-Signature: t=<timestamp>,v1=<hex> over "<timestamp>.<body>"
+	SchemeStripe = "stripe"
+
+	defaultTolerance = 5 * time.Minute
+)
+
+type hmacAuthenticator struct {
+	scheme          string
+	secret          []byte
+	header          string
+	hashFunc        func() hash.Hash
+	encoding        string
+	prefix          string
+	timestampHeader string
+	tolerance       time.Duration
+	now             func() time.Time
+}
+
+func newHMAC(config HMACConfig) (*hmacAuthenticator, error) {
+	if config.Secret == "" {
+		return nil, fmt.Errorf("hmac authentication requires a secret")
+	}
+
+	switch config.Scheme {
+	case SchemeGitHub:
+		config.Header, config.Algorithm, config.Encoding, config.Prefix = "X-Hub-Signature-256", "sha256", "hex", "sha256="
+	case SchemeShopify:
+		config.Header, config.Algorithm, config.Encoding, config.Prefix = "X-Shopify-Hmac-Sha256", "sha256", "base64", ""
+	case SchemeStripe:
+		config.Header, config.Algorithm, config.Encoding, config.Prefix = "Stripe-Signature", "sha256", "hex", ""
+		if config.Tolerance == 0 {
+			config.Tolerance = defaultTolerance
+		}
+	case SchemeGeneric, "":
+		if config.Header == "" {
+			return nil, fmt.Errorf("hmac authentication requires a signature header")
+		}
+	default:
+		return nil, fmt.Errorf("unknown hmac scheme %q", config.Scheme)
+	}
+
+	var hashFunc func() hash.Hash
+	switch strings.ToLower(config.Algorithm) {
+	case "sha256", "":
+		hashFunc = sha256.New
+	case "sha512":
+		hashFunc = sha512.New
+	default:
+		return nil, fmt.Errorf("unsupported hmac algorithm %q", config.Algorithm)
+	}
+
+	switch config.Encoding {
+	case "hex", "base64":
+	case "":
+		config.Encoding = "hex"
+	default:
+		return nil, fmt.Errorf("unsupported hmac encoding %q", config.Encoding)
+	}
+
+	return &hmacAuthenticator{
+		scheme:          config.Scheme,
+		secret:          []byte(config.Secret),
+		header:          config.Header,
+		hashFunc:        hashFunc,
+		encoding:        config.Encoding,
+		prefix:          config.Prefix,
+		timestampHeader: config.TimestampHeader,
+		tolerance:       config.Tolerance,
+		now:             time.Now,
+	}, nil
+}
+
+func (h *hmacAuthenticator) Authenticate(r *http.Request, body []byte) (string, error) {
+	headerValue := r.Header.Get(h.header)
+	if headerValue == "" {
+		return "", fmt.Errorf("missing signature header %s", h.header)
+	}
+
+	var timestamp string
+	var signatures []string
+	if h.scheme == SchemeStripe {
+		timestamp, signatures = parseStripeHeader(headerValue)
+	} else {
+		if !strings.HasPrefix(headerValue, h.prefix) {
+			return "", fmt.Errorf("signature header %s does not start with %q", h.header, h.prefix)
+		}
+		signatures = []string{strings.TrimPrefix(headerValue, h.prefix)}
+		if h.timestampHeader != "" {
+			timestamp = r.Header.Get(h.timestampHeader)
+		}
+	}
+
+	signedPayload := body
+	if h.scheme == SchemeStripe || h.timestampHeader != "" {
+		if err := h.checkTimestamp(timestamp); err != nil {
+			return "", err
+		}
+		signedPayload = append([]byte(timestamp+"."), body...)
+	}
+
+	mac := hmac.New(h.hashFunc, h.secret)
+	mac.Write(signedPayload)
+	expected := mac.Sum(nil)
+
+	for _, signature := range signatures {
+		supplied, err := h.decode(signature)
+		if err != nil {
+			continue
+		}
+		if hmac.Equal(supplied, expected) {
+			return "hmac", nil
+		}
+	}
+
+	return "", fmt.Errorf("signature does not match, either the body is not authentic or the secret is not aligned")
+}
+
+//checkTimestamp - unix timestamp in seconds within the tolerance, protects against replays
+func (h *hmacAuthenticator) checkTimestamp(timestamp string) error {
+	if timestamp == "" {
+		return fmt.Errorf("missing signature timestamp")
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid signature timestamp %q", timestamp)
+	}
+
+	if h.tolerance > 0 {
+		age := h.now().Sub(time.Unix(seconds, 0))
+		if age > h.tolerance || age < -h.tolerance {
+			return fmt.Errorf("signature timestamp %s is outside of the tolerance of %s", timestamp, h.tolerance)
+		}
+	}
+	return nil
+}
+
+func (h *hmacAuthenticator) decode(signature string) ([]byte, error) {
+	if h.encoding == "base64" {
+		return base64.StdEncoding.DecodeString(signature)
+	}
+	return hex.DecodeString(signature)
+}
+
+//parseStripeHeader - t=<timestamp>,v1=<signature>[,v1=<signature>]
+func parseStripeHeader(value string) (timestamp string, signatures []string) {
+	for _, item := range strings.Split(value, ",") {
+		parts := strings.SplitN(strings.TrimSpace(item), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		switch parts[0] {
+		case "t":
+			timestamp = parts[1]
+		case "v1":
+			signatures = append(signatures, parts[1])
+		}
+	}
+	return timestamp, signatures
+}
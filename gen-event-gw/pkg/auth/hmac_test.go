@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const (
+	hmacSecret = "kyma4ever"
+	hmacBody   = `{"order":{"id":1}}`
+)
+
+func sign(payload string) []byte {
+	mac := hmac.New(sha256.New, []byte(hmacSecret))
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+func TestHMAC_GitHub(t *testing.T) {
+	authenticator, err := New(Config{Mode: ModeHMAC, HMAC: HMACConfig{Scheme: SchemeGitHub, Secret: hmacSecret}})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/events", nil)
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(sign(hmacBody)))
+	if _, err := authenticator.Authenticate(req, []byte(hmacBody)); err != nil {
+		t.Errorf("valid signature rejected: %s", err.Error())
+	}
+
+	if _, err := authenticator.Authenticate(req, []byte(hmacBody+" ")); err == nil {
+		t.Error("signature of modified body accepted")
+	}
+}
+
+func TestHMAC_Shopify(t *testing.T) {
+	authenticator, _ := New(Config{Mode: ModeHMAC, HMAC: HMACConfig{Scheme: SchemeShopify, Secret: hmacSecret}})
+
+	req := httptest.NewRequest(http.MethodPost, "/events", nil)
+	req.Header.Set("X-Shopify-Hmac-Sha256", base64.StdEncoding.EncodeToString(sign(hmacBody)))
+	if _, err := authenticator.Authenticate(req, []byte(hmacBody)); err != nil {
+		t.Errorf("valid signature rejected: %s", err.Error())
+	}
+}
+
+func TestHMAC_Stripe(t *testing.T) {
+	a, _ := New(Config{Mode: ModeHMAC, HMAC: HMACConfig{Scheme: SchemeStripe, Secret: hmacSecret}})
+	authenticator := a.(*hmacAuthenticator)
+	now := time.Unix(1577836800, 0)
+	authenticator.now = func() time.Time { return now }
+
+	timestamp := fmt.Sprint(now.Unix())
+	signature := hex.EncodeToString(sign(timestamp + "." + hmacBody))
+
+	req := httptest.NewRequest(http.MethodPost, "/events", nil)
+	req.Header.Set("Stripe-Signature", fmt.Sprintf("t=%s,v1=deadbeef,v1=%s", timestamp, signature))
+	if _, err := authenticator.Authenticate(req, []byte(hmacBody)); err != nil {
+		t.Errorf("valid signature rejected: %s", err.Error())
+	}
+
+	now = now.Add(10 * time.Minute)
+	if _, err := authenticator.Authenticate(req, []byte(hmacBody)); err == nil {
+		t.Error("signature outside of the tolerance accepted")
+	}
+}
+
+func TestHMAC_Generic(t *testing.T) {
+	authenticator, err := New(Config{Mode: ModeHMAC, HMAC: HMACConfig{
+		Secret:    hmacSecret,
+		Header:    "X-Signature",
+		Algorithm: "sha512",
+		Encoding:  "base64",
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	mac := hmac.New(sha512.New, []byte(hmacSecret))
+	mac.Write([]byte(hmacBody))
+
+	req := httptest.NewRequest(http.MethodPost, "/events", nil)
+	req.Header.Set("X-Signature", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	if _, err := authenticator.Authenticate(req, []byte(hmacBody)); err != nil {
+		t.Errorf("valid signature rejected: %s", err.Error())
+	}
+
+	req.Header.Del("X-Signature")
+	if _, err := authenticator.Authenticate(req, []byte(hmacBody)); err == nil {
+		t.Error("missing signature accepted")
+	}
+}
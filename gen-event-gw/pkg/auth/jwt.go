@@ -0,0 +1,318 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//clockSkew - leeway for exp and nbf checks
+const clockSkew = 30 * time.Second
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Subject   string          `json:"sub"`
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *int64          `json:"exp"`
+	NotBefore *int64          `json:"nbf"`
+}
+
+//signingKey - public key of the jwks with the algorithms it may be used with
+type signingKey struct {
+	publicKey crypto.PublicKey
+	algs      map[string]bool
+}
+
+//keyAlgs - the algorithms are bound to the key type and the curve, an alg of the jwk restricts them further
+var keyAlgs = map[string][]string{
+	"RSA":   {"RS256", "RS384", "RS512"},
+	"P-256": {"ES256"},
+	"P-384": {"ES384"},
+	"P-521": {"ES512"},
+}
+
+type jwtAuthenticator struct {
+	keys     map[string]*signingKey
+	issuer   string
+	audience string
+	now      func() time.Time
+}
+
+func newJWT(jwksFile string, issuer string, audience string) (*jwtAuthenticator, error) {
+	if jwksFile == "" {
+		return nil, fmt.Errorf("jwt authentication requires a jwks file")
+	}
+
+	data, err := ioutil.ReadFile(jwksFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading jwks file: %s", err.Error())
+	}
+
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &jwtAuthenticator{
+		keys:     keys,
+		issuer:   issuer,
+		audience: audience,
+		now:      time.Now,
+	}, nil
+}
+
+func parseJWKS(data []byte) (map[string]*signingKey, error) {
+	var set jwks
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("error in jwks json: %s", err.Error())
+	}
+
+	keys := map[string]*signingKey{}
+	for i, key := range set.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+
+		var publicKey crypto.PublicKey
+		var algs []string
+		var err error
+		switch key.Kty {
+		case "RSA":
+			publicKey, err = rsaKey(key)
+			algs = keyAlgs["RSA"]
+		case "EC":
+			publicKey, err = ecKey(key)
+			algs = keyAlgs[key.Crv]
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error parsing key %d of jwks: %s", i, err.Error())
+		}
+
+		signing := &signingKey{publicKey: publicKey, algs: map[string]bool{}}
+		for _, alg := range algs {
+			if key.Alg == "" || key.Alg == alg {
+				signing.algs[alg] = true
+			}
+		}
+		if len(signing.algs) == 0 {
+			return nil, fmt.Errorf("algorithm %q of key %d of jwks does not match the key type", key.Alg, i)
+		}
+		//tokens select their key by kid, an ambiguous kid would silently replace a key
+		if key.Kid == "" {
+			return nil, fmt.Errorf("key %d of jwks has no kid", i)
+		}
+		if _, exists := keys[key.Kid]; exists {
+			return nil, fmt.Errorf("duplicate kid %q of key %d of jwks", key.Kid, i)
+		}
+		keys[key.Kid] = signing
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("jwks does not contain any usable signing key")
+	}
+	return keys, nil
+}
+
+func rsaKey(key jwk) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(key.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := base64.RawURLEncoding.DecodeString(key.E)
+	if err != nil {
+		return nil, err
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+}
+
+func ecKey(key jwk) (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch key.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", key.Crv)
+	}
+
+	x, err := base64.RawURLEncoding.DecodeString(key.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := base64.RawURLEncoding.DecodeString(key.Y)
+	if err != nil {
+		return nil, err
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+}
+
+func (j *jwtAuthenticator) Authenticate(r *http.Request, body []byte) (string, error) {
+	token, err := bearerToken(r)
+	if err != nil {
+		return "", err
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("malformed jwt")
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return "", fmt.Errorf("malformed jwt header: %s", err.Error())
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("malformed jwt signature: %s", err.Error())
+	}
+
+	key, ok := j.keys[header.Kid]
+	if !ok {
+		return "", fmt.Errorf("unknown jwt key id %q", header.Kid)
+	}
+
+	if !key.algs[header.Alg] {
+		return "", fmt.Errorf("jwt algorithm %q is not allowed for key %q", header.Alg, header.Kid)
+	}
+
+	if err := verifySignature(header.Alg, key.publicKey, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return "", err
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return "", fmt.Errorf("malformed jwt claims: %s", err.Error())
+	}
+
+	if err := j.validateClaims(&claims); err != nil {
+		return "", err
+	}
+
+	return claims.Subject, nil
+}
+
+func (j *jwtAuthenticator) validateClaims(claims *jwtClaims) error {
+	now := j.now()
+	if claims.ExpiresAt == nil {
+		return fmt.Errorf("jwt does not expire")
+	}
+	if now.After(time.Unix(*claims.ExpiresAt, 0).Add(clockSkew)) {
+		return fmt.Errorf("jwt is expired")
+	}
+	if claims.NotBefore != nil && now.Before(time.Unix(*claims.NotBefore, 0).Add(-clockSkew)) {
+		return fmt.Errorf("jwt is not valid yet")
+	}
+	if j.issuer != "" && claims.Issuer != j.issuer {
+		return fmt.Errorf("unexpected jwt issuer %q", claims.Issuer)
+	}
+	if j.audience != "" && !containsAudience(claims.Audience, j.audience) {
+		return fmt.Errorf("jwt is not issued for audience %q", j.audience)
+	}
+	return nil
+}
+
+//containsAudience - aud is either a single string or an array of strings
+func containsAudience(raw json.RawMessage, audience string) bool {
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return single == audience
+	}
+
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		for _, item := range list {
+			if item == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signed []byte, signature []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512", "ES512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported jwt algorithm %q", alg)
+	}
+
+	hasher := hash.New()
+	hasher.Write(signed)
+	digest := hasher.Sum(nil)
+
+	switch publicKey := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") {
+			return fmt.Errorf("jwt algorithm %q does not match rsa key", alg)
+		}
+		if err := rsa.VerifyPKCS1v15(publicKey, hash, digest, signature); err != nil {
+			return fmt.Errorf("invalid jwt signature")
+		}
+	case *ecdsa.PublicKey:
+		if !strings.HasPrefix(alg, "ES") {
+			return fmt.Errorf("jwt algorithm %q does not match ec key", alg)
+		}
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return fmt.Errorf("invalid jwt signature")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(publicKey, digest, r, s) {
+			return fmt.Errorf("invalid jwt signature")
+		}
+	default:
+		return fmt.Errorf("unsupported jwt key")
+	}
+	return nil
+}
+
+func decodeSegment(segment string, target interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
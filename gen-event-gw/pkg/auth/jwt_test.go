@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func encodeSegment(value interface{}) string {
+	data, _ := json.Marshal(value)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func rsaToken(key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	signed := encodeSegment(map[string]string{"alg": "RS256", "kid": kid}) + "." + encodeSegment(claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func ecToken(key *ecdsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	return ecTokenWithAlg(key, "ES256", crypto.SHA256, kid, claims)
+}
+
+func ecTokenWithAlg(key *ecdsa.PrivateKey, alg string, hash crypto.Hash, kid string, claims map[string]interface{}) string {
+	signed := encodeSegment(map[string]string{"alg": alg, "kid": kid}) + "." + encodeSegment(claims)
+	hasher := hash.New()
+	hasher.Write([]byte(signed))
+	r, s, _ := ecdsa.Sign(rand.Reader, key, hasher.Sum(nil))
+	signature := make([]byte, 64)
+	rBytes, sBytes := r.Bytes(), s.Bytes()
+	copy(signature[32-len(rBytes):32], rBytes)
+	copy(signature[64-len(sBytes):], sBytes)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestJWT(t *testing.T) {
+	rsaPrivate, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecPrivate, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	keySet := fmt.Sprintf(`{"keys":[
+		{"kty":"RSA","kid":"rsa","use":"sig","n":%q,"e":%q},
+		{"kty":"EC","kid":"ec","crv":"P-256","x":%q,"y":%q}
+	]}`,
+		base64.RawURLEncoding.EncodeToString(rsaPrivate.N.Bytes()),
+		base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaPrivate.E)).Bytes()),
+		base64.RawURLEncoding.EncodeToString(ecPrivate.X.Bytes()),
+		base64.RawURLEncoding.EncodeToString(ecPrivate.Y.Bytes()))
+
+	keys, err := parseJWKS([]byte(keySet))
+	if err != nil {
+		t.Fatalf("jwks parsing failed: %s", err.Error())
+	}
+
+	authenticator := &jwtAuthenticator{keys: keys, issuer: "https://issuer", audience: "gen-event-gw", now: time.Now}
+	valid := map[string]interface{}{
+		"sub": "webhook-client",
+		"iss": "https://issuer",
+		"aud": []string{"other", "gen-event-gw"},
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+
+	tests := []struct {
+		name      string
+		token     string
+		expectErr bool
+	}{
+		{"rsa", rsaToken(rsaPrivate, "rsa", valid), false},
+		{"ec", ecToken(ecPrivate, "ec", valid), false},
+		{"unknown kid", rsaToken(rsaPrivate, "other", valid), true},
+		{"wrong key", rsaToken(rsaPrivate, "ec", valid), true},
+		{"expired", rsaToken(rsaPrivate, "rsa", map[string]interface{}{"sub": "x", "iss": "https://issuer", "aud": "gen-event-gw", "exp": time.Now().Add(-time.Hour).Unix()}), true},
+		{"without exp", rsaToken(rsaPrivate, "rsa", map[string]interface{}{"sub": "webhook-client", "iss": "https://issuer", "aud": "gen-event-gw"}), true},
+		{"alg not matching curve", ecTokenWithAlg(ecPrivate, "ES384", crypto.SHA384, "ec", valid), true},
+		{"wrong issuer", rsaToken(rsaPrivate, "rsa", map[string]interface{}{"sub": "x", "iss": "https://other", "aud": "gen-event-gw", "exp": time.Now().Add(time.Hour).Unix()}), true},
+		{"wrong audience", rsaToken(rsaPrivate, "rsa", map[string]interface{}{"sub": "x", "iss": "https://issuer", "aud": "other", "exp": time.Now().Add(time.Hour).Unix()}), true},
+		{"malformed", "abc.def", true},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/events", nil)
+		req.Header.Set("Authorization", "Bearer "+test.token)
+
+		principal, err := authenticator.Authenticate(req, nil)
+		if test.expectErr && err == nil {
+			t.Errorf("%s: token should be rejected", test.name)
+		}
+		if !test.expectErr && (err != nil || principal != "webhook-client") {
+			t.Errorf("%s: token should be accepted: %v", test.name, err)
+		}
+	}
+}
+
+func TestParseJWKS_Alg(t *testing.T) {
+	ecPrivate, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	x := base64.RawURLEncoding.EncodeToString(ecPrivate.X.Bytes())
+	y := base64.RawURLEncoding.EncodeToString(ecPrivate.Y.Bytes())
+
+	if _, err := parseJWKS([]byte(fmt.Sprintf(`{"keys":[{"kty":"EC","kid":"ec","alg":"ES256","crv":"P-256","x":%q,"y":%q}]}`, x, y))); err != nil {
+		t.Errorf("alg matching the curve should be accepted: %s", err.Error())
+	}
+	if _, err := parseJWKS([]byte(fmt.Sprintf(`{"keys":[{"kty":"EC","kid":"ec","alg":"RS256","crv":"P-256","x":%q,"y":%q}]}`, x, y))); err == nil {
+		t.Error("alg not matching the key type should be rejected")
+	}
+}
+
+func TestParseJWKS_Kid(t *testing.T) {
+	ecPrivate, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	x := base64.RawURLEncoding.EncodeToString(ecPrivate.X.Bytes())
+	y := base64.RawURLEncoding.EncodeToString(ecPrivate.Y.Bytes())
+	key := func(kid string) string {
+		return fmt.Sprintf(`{"kty":"EC","kid":%q,"crv":"P-256","x":%q,"y":%q}`, kid, x, y)
+	}
+
+	if _, err := parseJWKS([]byte(`{"keys":[` + key("a") + `,` + key("b") + `]}`)); err != nil {
+		t.Errorf("distinct kids should be accepted: %s", err.Error())
+	}
+	if _, err := parseJWKS([]byte(`{"keys":[` + key("") + `]}`)); err == nil {
+		t.Error("key without kid should be rejected")
+	}
+	if _, err := parseJWKS([]byte(`{"keys":[` + key("a") + `,` + key("a") + `]}`)); err == nil {
+		t.Error("duplicate kid should be rejected")
+	}
+}
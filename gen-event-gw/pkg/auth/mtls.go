@@ -0,0 +1,23 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+)
+
+//mtls - relies on the server verifying the client certificate against the configured client CA
+type mtls struct {
+	allowedSubjects map[string]bool
+}
+
+func (m *mtls) Authenticate(r *http.Request, body []byte) (string, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return "", fmt.Errorf("missing verified client certificate")
+	}
+
+	subject := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	if len(m.allowedSubjects) > 0 && !m.allowedSubjects[subject] {
+		return "", fmt.Errorf("client certificate subject %q is not allowed", subject)
+	}
+	return subject, nil
+}
@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const bearerPrefix = "Bearer "
+
+type basic struct {
+	username string
+	password string
+}
+
+func newBasic(username string, password string) (*basic, error) {
+	if username == "" || password == "" {
+		return nil, fmt.Errorf("basic authentication requires username and password")
+	}
+	return &basic{username: username, password: password}, nil
+}
+
+func (b *basic) Authenticate(r *http.Request, body []byte) (string, error) {
+	user, pass, ok := r.BasicAuth()
+
+	if !ok || subtle.ConstantTimeCompare([]byte(user), []byte(b.username)) != 1 || subtle.ConstantTimeCompare([]byte(pass), []byte(b.password)) != 1 {
+		return "", fmt.Errorf("invalid basic auth credentials")
+	}
+	return user, nil
+}
+
+type bearer struct {
+	tokens []string
+}
+
+func newBearer(tokens []string) (*bearer, error) {
+	tokens = nonEmpty(tokens)
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("bearer authentication requires at least one token")
+	}
+	return &bearer{tokens: tokens}, nil
+}
+
+func (b *bearer) Authenticate(r *http.Request, body []byte) (string, error) {
+	token, err := bearerToken(r)
+	if err != nil {
+		return "", err
+	}
+
+	index := matchSecret(b.tokens, token)
+	if index < 0 {
+		return "", fmt.Errorf("invalid bearer token")
+	}
+	return fmt.Sprintf("bearer-%d", index), nil
+}
+
+type apiKey struct {
+	keys   []string
+	header string
+	query  string
+}
+
+func newAPIKey(keys []string, header string, query string) (*apiKey, error) {
+	keys = nonEmpty(keys)
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("api key authentication requires at least one key")
+	}
+	if header == "" && query == "" {
+		return nil, fmt.Errorf("api key authentication requires a header or query parameter")
+	}
+	return &apiKey{keys: keys, header: header, query: query}, nil
+}
+
+func (a *apiKey) Authenticate(r *http.Request, body []byte) (string, error) {
+	var key string
+	if a.header != "" {
+		key = r.Header.Get(a.header)
+	}
+	if key == "" && a.query != "" {
+		key = r.URL.Query().Get(a.query)
+	}
+	if key == "" {
+		return "", fmt.Errorf("missing api key")
+	}
+
+	index := matchSecret(a.keys, key)
+	if index < 0 {
+		return "", fmt.Errorf("invalid api key")
+	}
+	return fmt.Sprintf("apikey-%d", index), nil
+}
+
+func bearerToken(r *http.Request) (string, error) {
+	authorization := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authorization, bearerPrefix) {
+		return "", fmt.Errorf("missing bearer token")
+	}
+	return strings.TrimSpace(strings.TrimPrefix(authorization, bearerPrefix)), nil
+}
+
+//matchSecret - compares against all secrets in constant time and returns the index of the match or -1
+func matchSecret(secrets []string, candidate string) int {
+	match := -1
+	for i, secret := range secrets {
+		if subtle.ConstantTimeCompare([]byte(secret), []byte(candidate)) == 1 {
+			match = i
+		}
+	}
+	return match
+}
+
+func nonEmpty(values []string) []string {
+	var result []string
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
@@ -0,0 +1,255 @@
+package capture
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	filePrefix = "capture-"
+	fileSuffix = ".ndjson"
+
+	base64Encoding = "base64"
+	redacted       = "REDACTED"
+)
+
+//Config - settings of the capture archive
+type Config struct {
+	Dir         string
+	MaxFileSize int64
+	MaxFiles    int
+}
+
+//Record - a received request and the outcome of processing it
+type Record struct {
+	Time         time.Time   `json:"time"`
+	App          string      `json:"app"`
+	Batch        bool        `json:"batch,omitempty"`
+	Headers      http.Header `json:"headers"`
+	Body         string      `json:"body"`
+	BodyEncoding string      `json:"bodyEncoding,omitempty"`
+	EventType    string      `json:"eventType,omitempty"`
+	EventID      string      `json:"eventId,omitempty"`
+	Status       int         `json:"status"`
+	Outcome      string      `json:"outcome"`
+}
+
+//SetBody - bodies that are not valid UTF-8 are stored base64 encoded
+func (r *Record) SetBody(body []byte) {
+	if utf8.Valid(body) {
+		r.Body = string(body)
+		r.BodyEncoding = ""
+		return
+	}
+	r.Body = base64.StdEncoding.EncodeToString(body)
+	r.BodyEncoding = base64Encoding
+}
+
+//RawBody - the body as it was received
+func (r *Record) RawBody() ([]byte, error) {
+	if r.BodyEncoding == base64Encoding {
+		return base64.StdEncoding.DecodeString(r.Body)
+	}
+	return []byte(r.Body), nil
+}
+
+//Redact - copy of the headers without the values of the given credential headers
+func Redact(headers http.Header, credentialHeaders ...string) http.Header {
+	result := make(http.Header, len(headers))
+	for name, values := range headers {
+		result[name] = append([]string(nil), values...)
+	}
+
+	for _, name := range append([]string{"Authorization", "Proxy-Authorization", "Cookie"}, credentialHeaders...) {
+		if name == "" {
+			continue
+		}
+		if _, ok := result[http.CanonicalHeaderKey(name)]; ok {
+			result.Set(name, redacted)
+		}
+	}
+	return result
+}
+
+//Archive - writes records to NDJSON files, a new file is started once the current file exceeds the maximum size
+//Only the newest files are kept
+type Archive struct {
+	config Config
+
+	lock sync.Mutex
+	file *os.File
+	size int64
+}
+
+//New - creates the archive directory
+func New(config Config) (*Archive, error) {
+	if config.Dir == "" {
+		return nil, fmt.Errorf("capture directory is not configured")
+	}
+	if config.MaxFileSize < 1 {
+		return nil, fmt.Errorf("capture files need a positive size, got %d", config.MaxFileSize)
+	}
+	if config.MaxFiles < 1 {
+		return nil, fmt.Errorf("capture archive needs at least one file, got %d", config.MaxFiles)
+	}
+
+	if err := os.MkdirAll(config.Dir, 0700); err != nil {
+		return nil, fmt.Errorf("error creating capture directory: %s", err.Error())
+	}
+
+	return &Archive{config: config}, nil
+}
+
+//Write - appends the record to the current file
+func (a *Archive) Write(record *Record) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.file == nil || a.size+int64(len(line)) > a.config.MaxFileSize {
+		if err := a.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := a.file.Write(line)
+	a.size += int64(n)
+	return err
+}
+
+//Close - closes the current file
+func (a *Archive) Close() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.file == nil {
+		return nil
+	}
+	err := a.file.Close()
+	a.file = nil
+	return err
+}
+
+//rotate - starts a new file and removes the oldest files
+func (a *Archive) rotate() error {
+	if a.file != nil {
+		if err := a.file.Close(); err != nil {
+			return err
+		}
+		a.file = nil
+	}
+
+	//the timestamp keeps the file names in the order they were written
+	name := filePrefix + time.Now().UTC().Format("20060102T150405.000000000") + fileSuffix
+	file, err := os.OpenFile(filepath.Join(a.config.Dir, name), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("error creating capture file: %s", err.Error())
+	}
+	a.file = file
+	a.size = 0
+
+	files, err := Files(a.config.Dir)
+	if err != nil {
+		return err
+	}
+	for len(files) > a.config.MaxFiles {
+		if err := os.Remove(files[0]); err != nil {
+			return fmt.Errorf("error removing capture file: %s", err.Error())
+		}
+		files = files[1:]
+	}
+	return nil
+}
+
+//Files - capture files of the directory, oldest first
+func Files(dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading capture directory: %s", err.Error())
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), filePrefix) && strings.HasSuffix(entry.Name(), fileSuffix) {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+//Read - calls fn for every record of the capture file or of all capture files of the directory
+func Read(path string, fn func(record *Record) error) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("error reading capture archive: %s", err.Error())
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		if files, err = Files(path); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range files {
+		if err := readFile(file, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readFile(file string, fn func(record *Record) error) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("error reading capture file: %s", err.Error())
+	}
+	//noinspection GoUnhandledErrorResult
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	for lineNumber := 1; ; lineNumber++ {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return fmt.Errorf("error reading capture file: %s", readErr.Error())
+		}
+
+		if len(bytes.TrimSpace(line)) > 0 {
+			var record Record
+			err := json.Unmarshal(line, &record)
+			//a partially written last line is ignored
+			if err != nil && readErr == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("invalid record in line %d of %s: %s", lineNumber, file, err.Error())
+			}
+			if err := fn(&record); err != nil {
+				return err
+			}
+		}
+
+		if readErr == io.EOF {
+			return nil
+		}
+	}
+}
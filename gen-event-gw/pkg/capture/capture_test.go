@@ -0,0 +1,112 @@
+package capture
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+
+	_, err := New(Config{Dir: "", MaxFileSize: 1, MaxFiles: 1})
+	if err == nil {
+		t.Error("missing directory should be rejected")
+	}
+
+	_, err = New(Config{Dir: "x", MaxFileSize: 1, MaxFiles: 0})
+	if err == nil {
+		t.Error("archive without files should be rejected")
+	}
+}
+
+func TestArchive(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "capture")
+	defer os.RemoveAll(dir)
+
+	a, err := New(Config{Dir: dir, MaxFileSize: 300, MaxFiles: 3})
+	if err != nil {
+		t.Fatalf("archive creation failed: %s", err.Error())
+	}
+
+	for i := 0; i < 10; i++ {
+		record := &Record{App: "shop", Status: http.StatusOK, Outcome: fmt.Sprintf("event %d", i)}
+		record.SetBody([]byte(fmt.Sprintf(`{"type":"order.created","index":%d}`, i)))
+		if err := a.Write(record); err != nil {
+			t.Fatalf("writing record failed: %s", err.Error())
+		}
+	}
+	a.Close()
+
+	files, _ := Files(dir)
+	if len(files) != 3 {
+		t.Errorf("expected 3 files, got %d", len(files))
+	}
+
+	var outcomes []string
+	err = Read(dir, func(record *Record) error {
+		outcomes = append(outcomes, record.Outcome)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("reading archive failed: %s", err.Error())
+	}
+	if len(outcomes) == 0 || outcomes[len(outcomes)-1] != "event 9" {
+		t.Errorf("expected the newest records in order, got %v", outcomes)
+	}
+	for i := 1; i < len(outcomes); i++ {
+		if outcomes[i-1] >= outcomes[i] {
+			t.Errorf("records out of order: %v", outcomes)
+		}
+	}
+
+	//a partially written last line is ignored
+	last := files[len(files)-1]
+	f, _ := os.OpenFile(last, os.O_APPEND|os.O_WRONLY, 0600)
+	f.WriteString(`{"app":"sh`)
+	f.Close()
+	if err := Read(last, func(record *Record) error { return nil }); err != nil {
+		t.Errorf("partial line should be ignored: %s", err.Error())
+	}
+
+	if err := Read(filepath.Join(dir, "missing.ndjson"), func(record *Record) error { return nil }); err == nil {
+		t.Error("reading a missing file should fail")
+	}
+}
+
+func TestRecord_Body(t *testing.T) {
+
+	binary := []byte{0xff, 0x00, 0xfe}
+	record := &Record{}
+	record.SetBody(binary)
+	if record.BodyEncoding != base64Encoding {
+		t.Errorf("binary body should be base64 encoded")
+	}
+	body, err := record.RawBody()
+	if err != nil || string(body) != string(binary) {
+		t.Errorf("unexpected body %v", body)
+	}
+
+	record.SetBody([]byte(`{"a":1}`))
+	if record.Body != `{"a":1}` || record.BodyEncoding != "" {
+		t.Errorf("text body should be stored unchanged, got %+v", record)
+	}
+}
+
+func TestRedact(t *testing.T) {
+
+	headers := http.Header{}
+	headers.Set("Authorization", "Bearer secret")
+	headers.Set("X-Api-Key", "key")
+	headers.Set("Content-Type", "application/json")
+
+	redactedHeaders := Redact(headers, "X-API-Key")
+	if redactedHeaders.Get("Authorization") != redacted || redactedHeaders.Get("X-Api-Key") != redacted {
+		t.Errorf("credentials should be redacted, got %v", redactedHeaders)
+	}
+	if redactedHeaders.Get("Content-Type") != "application/json" || headers.Get("Authorization") != "Bearer secret" {
+		t.Errorf("other headers and the original headers should be unchanged")
+	}
+}
@@ -2,37 +2,647 @@ package config
 
 import (
 	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
 
-	log "github.com/sirupsen/logrus"
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/auth"
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/limit"
+
+	"gopkg.in/yaml.v2"
 )
 
-//Config - configuration data for app
+const (
+	//DefaultEventPublishURL - kyma event bus inside the cluster
+	DefaultEventPublishURL = "http://event-publish-service.kyma-system.svc.cluster.local:8080/v1/events"
+
+	//SchemaActionReject - events violating their schema are rejected with 422
+	SchemaActionReject = "reject"
+	//SchemaActionQuarantine - events violating their schema are acknowledged and written to the quarantine file
+	SchemaActionQuarantine = "quarantine"
+
+	//TargetKyma - kyma event bus, the response has to be JSON
+	TargetKyma = "kyma"
+	//TargetHTTP - plain HTTP endpoint, the response is ignored
+	TargetHTTP = "http"
+	//TargetFile - events are appended to a NDJSON file
+	TargetFile = "file"
+
+	envPrefix = "GEN_EVENT_GW_"
+)
+
+var appNameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+//Config - configuration data for the gateway
 type Config struct {
-	AppName         *string
-	Password        *string
-	UserName        *string
-	EventTypeQuery  *string
-	EventPublishURL *string
+	Port               int           `yaml:"port"`
+	ManagementPort     int           `yaml:"managementPort"`
+	ReadTimeout        time.Duration `yaml:"readTimeout"`
+	WriteTimeout       time.Duration `yaml:"writeTimeout"`
+	IdleTimeout        time.Duration `yaml:"idleTimeout"`
+	ShutdownTimeout    time.Duration `yaml:"shutdownTimeout"`
+	ReadyCheckInterval time.Duration `yaml:"readyCheckInterval"`
+	TLS                TLSConfig     `yaml:"tls"`
+	Outbox             OutboxConfig  `yaml:"outbox"`
+	Capture            CaptureConfig `yaml:"capture"`
+	Limits             LimitsConfig  `yaml:"limits"`
+	Apps               []AppConfig   `yaml:"apps"`
+}
+
+//AppConfig - configuration of a single application served by the gateway
+type AppConfig struct {
+	Name            string         `yaml:"name"`
+	EventTypeQuery  string         `yaml:"eventTypeQuery"`
+	EventTypeRules  string         `yaml:"eventTypeRules"`
+	TransformConfig string         `yaml:"transformConfig"`
+	EventPublishURL string         `yaml:"eventPublishURL"`
+	EventFormat     string         `yaml:"eventFormat"`
+	ForwardTimeout  time.Duration  `yaml:"forwardTimeout"`
+	Auth            auth.Config    `yaml:"auth"`
+	Dedup           DedupConfig    `yaml:"dedup"`
+	Batch           BatchConfig    `yaml:"batch"`
+	Schema          SchemaConfig   `yaml:"schema"`
+	Limits          AppLimits      `yaml:"limits"`
+	Targets         []TargetConfig `yaml:"targets"`
+	Routes          []RouteConfig  `yaml:"routes"`
+}
+
+//TargetConfig - destination the events of an app are routed to
+//Without targets the events are forwarded to the event publish url of the app
+type TargetConfig struct {
+	Name                  string            `yaml:"name"`
+	Type                  string            `yaml:"type"`
+	URL                   string            `yaml:"url"`
+	Format                string            `yaml:"format"`
+	File                  string            `yaml:"file"`
+	Timeout               time.Duration     `yaml:"timeout"`
+	Headers               map[string]string `yaml:"headers"`
+	Auth                  TargetAuth        `yaml:"auth"`
+	TLS                   TargetTLS         `yaml:"tls"`
+	MaxConcurrentForwards int               `yaml:"maxConcurrentForwards"`
+}
+
+//TargetAuth - credentials sent to a target, a bearer token takes precedence over basic auth
+type TargetAuth struct {
+	Username    string `yaml:"username"`
+	Password    string `yaml:"password"`
+	BearerToken string `yaml:"bearerToken"`
+}
+
+//TargetTLS - verification of the certificate of a target
+type TargetTLS struct {
+	CAFile             string `yaml:"caFile"`
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify"`
+}
+
+//RouteConfig - sends events matching all conditions to the targets
+//Event types are matched with path.Match patterns, the predicate is a gjson path evaluated on the event data
+type RouteConfig struct {
+	EventTypes []string `yaml:"eventTypes"`
+	Predicate  string   `yaml:"predicate"`
+	Equals     *string  `yaml:"equals"`
+	Targets    []string `yaml:"targets"`
+}
+
+//LimitsConfig - limits shared by all apps, rate limits are disabled if the rate is 0
+type LimitsConfig struct {
+	MaxBodySize    int64   `yaml:"maxBodySize"`
+	Rate           float64 `yaml:"rate"`
+	Burst          int     `yaml:"burst"`
+	ClientIPHeader string  `yaml:"clientIPHeader"`
+}
+
+//AppLimits - limits of a single app, the rate limit applies to each client ip or principal
+type AppLimits struct {
+	MaxBodySize           int64   `yaml:"maxBodySize"`
+	Rate                  float64 `yaml:"rate"`
+	Burst                 int     `yaml:"burst"`
+	Key                   string  `yaml:"key"`
+	MaxConcurrentForwards int     `yaml:"maxConcurrentForwards"`
+}
+
+//SchemaConfig - validation of the received data against the JSON schema of the event type
+type SchemaConfig struct {
+	Config         string `yaml:"config"`
+	Action         string `yaml:"action"`
+	QuarantineFile string `yaml:"quarantineFile"`
+}
+
+//BatchConfig - splitting and processing of batches received on the batch endpoint
+type BatchConfig struct {
+	Path        string `yaml:"path"`
+	Concurrency int    `yaml:"concurrency"`
+}
+
+//DedupConfig - duplicate detection of redelivered events
+//The key is read from the header, the gjson path or is the hash of the body, in that order
+type DedupConfig struct {
+	Enabled    bool          `yaml:"enabled"`
+	Header     string        `yaml:"header"`
+	Path       string        `yaml:"path"`
+	TTL        time.Duration `yaml:"ttl"`
+	MaxEntries int           `yaml:"maxEntries"`
+	File       string        `yaml:"file"`
+}
+
+//TLSConfig - serves the events endpoint via https if a certificate is configured
+type TLSConfig struct {
+	CertFile     string `yaml:"certFile"`
+	KeyFile      string `yaml:"keyFile"`
+	ClientCAFile string `yaml:"clientCAFile"`
+}
+
+//OutboxConfig - configuration of the optional on-disk outbox
+type OutboxConfig struct {
+	Dir            string        `yaml:"dir"`
+	Workers        int           `yaml:"workers"`
+	MaxAttempts    int           `yaml:"maxAttempts"`
+	InitialBackoff time.Duration `yaml:"initialBackoff"`
+	MaxBackoff     time.Duration `yaml:"maxBackoff"`
+}
+
+//CaptureConfig - archive of the received requests, used to replay them
+type CaptureConfig struct {
+	Dir         string `yaml:"dir"`
+	MaxFileSize int64  `yaml:"maxFileSize"`
+	MaxFiles    int    `yaml:"maxFiles"`
+}
+
+//Load - reads the configuration from the YAML file given with -config, environment variables and flags
+//Flags take precedence over environment variables (GEN_EVENT_GW_<FLAG>), which take precedence over the file.
+//If an app name is supplied via flag or environment, an app built from the flags is served in addition to the apps of the file.
+func Load(args []string) (*Config, error) {
+	return LoadFlags(flag.NewFlagSet("gen-event-gw", flag.ContinueOnError), args)
+}
+
+//LoadFlags - like Load, flags of subcommands can be defined on the flag set beforehand
+func LoadFlags(fs *flag.FlagSet, args []string) (*Config, error) {
+	cfg := &Config{
+		Port:               8080,
+		ManagementPort:     8081,
+		ReadTimeout:        30 * time.Second,
+		WriteTimeout:       60 * time.Second,
+		IdleTimeout:        2 * time.Minute,
+		ShutdownTimeout:    30 * time.Second,
+		ReadyCheckInterval: 10 * time.Second,
+		Outbox: OutboxConfig{
+			Workers:        4,
+			MaxAttempts:    10,
+			InitialBackoff: time.Second,
+			MaxBackoff:     5 * time.Minute,
+		},
+		Capture: CaptureConfig{
+			MaxFileSize: 100 * 1024 * 1024,
+			MaxFiles:    10,
+		},
+		Limits: LimitsConfig{
+			MaxBodySize: 10 * 1024 * 1024,
+		},
+	}
+	app := AppConfig{}
+
+	configFile := fs.String("config", "", "Location of the YAML configuration file (optional)")
+
+	fs.IntVar(&cfg.Port, "port", cfg.Port, "Port serving the events endpoints")
+	fs.IntVar(&cfg.ManagementPort, "management-port", cfg.ManagementPort, "Port serving health checks and metrics")
+	fs.DurationVar(&cfg.ReadTimeout, "read-timeout", cfg.ReadTimeout, "Maximum duration for reading a request")
+	fs.DurationVar(&cfg.WriteTimeout, "write-timeout", cfg.WriteTimeout, "Maximum duration for processing a request and writing the response")
+	fs.DurationVar(&cfg.IdleTimeout, "idle-timeout", cfg.IdleTimeout, "Maximum duration an idle keep-alive connection is kept open")
+	fs.DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", cfg.ShutdownTimeout, "Maximum duration to wait for in-flight events on shutdown")
+	fs.DurationVar(&cfg.ReadyCheckInterval, "ready-check-interval", cfg.ReadyCheckInterval, "Interval of the reachability checks of the event buses reported by /ready (disabled if 0)")
+	fs.StringVar(&cfg.TLS.CertFile, "tls-cert-file", "", "Server certificate, the server listens for https if set")
+	fs.StringVar(&cfg.TLS.KeyFile, "tls-key-file", "", "Server certificate key")
+	fs.StringVar(&cfg.TLS.ClientCAFile, "tls-client-ca-file", "", "CA certificates used to verify client certificates")
+	fs.StringVar(&cfg.Outbox.Dir, "outbox-dir", "", "Directory of the outbox, events are acknowledged once persisted and forwarded in the background (disabled if empty)")
+	fs.IntVar(&cfg.Outbox.Workers, "outbox-workers", cfg.Outbox.Workers, "Number of workers forwarding events from the outbox")
+	fs.IntVar(&cfg.Outbox.MaxAttempts, "outbox-max-attempts", cfg.Outbox.MaxAttempts, "Maximum number of forwarding attempts before an event is moved to the dead letter file")
+	fs.DurationVar(&cfg.Outbox.InitialBackoff, "outbox-initial-backoff", cfg.Outbox.InitialBackoff, "Backoff after the first failed forwarding attempt")
+	fs.DurationVar(&cfg.Outbox.MaxBackoff, "outbox-max-backoff", cfg.Outbox.MaxBackoff, "Maximum backoff between forwarding attempts")
+
+	fs.StringVar(&cfg.Capture.Dir, "capture-dir", "", "Directory of the capture archive, every received request is recorded (disabled if empty)")
+	fs.Int64Var(&cfg.Capture.MaxFileSize, "capture-max-file-size", cfg.Capture.MaxFileSize, "Size in bytes after which a new capture file is started")
+	fs.IntVar(&cfg.Capture.MaxFiles, "capture-max-files", cfg.Capture.MaxFiles, "Number of capture files that are kept")
+
+	fs.Int64Var(&cfg.Limits.MaxBodySize, "max-body-size", cfg.Limits.MaxBodySize, "Maximum size in bytes of a request body, larger requests are rejected with 413")
+	fs.Float64Var(&cfg.Limits.Rate, "global-rate-limit", 0, "Requests per second accepted over all apps and sources, larger bursts are rejected with 429 (disabled if 0)")
+	fs.IntVar(&cfg.Limits.Burst, "global-rate-burst", 0, "Requests accepted at once over all apps and sources, defaults to the rate")
+	fs.StringVar(&cfg.Limits.ClientIPHeader, "client-ip-header", "", "Header the ingress appends the client ip to, e.g. X-Forwarded-For, the remote address is used if empty")
+
+	fs.StringVar(&app.Name, "app-name", "", "Application Name")
+	fs.StringVar(&app.EventTypeQuery, "event-type-query", "", "The json query based on the get function of https://github.com/tidwall/gjson")
+	fs.StringVar(&app.EventTypeRules, "event-type-rules", "", "Location of the event type rule file, takes precedence over the event type query")
+	fs.StringVar(&app.TransformConfig, "transform-config", "", "Location of the payload transformation config (optional)")
+	fs.StringVar(&app.EventPublishURL, "event-publish-url", DefaultEventPublishURL, "URL to forward incoming events to Kyma Eventing")
+	fs.StringVar(&app.EventFormat, "event-format", "legacy", "Format of the forwarded events (legacy, structured or binary)")
+
+	fs.StringVar(&app.Auth.Mode, "auth-mode", "", "Authentication of inbound requests (none, basic, hmac, bearer, apikey, jwt, mtls), defaults to basic if username and password are set, none has to be set explicitly")
+	fs.StringVar(&app.Auth.Username, "username", "", "Basic Auth UserName")
+	fs.StringVar(&app.Auth.Password, "password", "", "Basic Auth Password")
+	fs.StringVar(&app.Auth.HMAC.Scheme, "hmac-scheme", auth.SchemeGeneric, "HMAC signature scheme (generic, github, shopify, stripe)")
+	fs.StringVar(&app.Auth.HMAC.Secret, "hmac-secret", "", "Shared secret of the HMAC signature")
+	fs.StringVar(&app.Auth.HMAC.Header, "hmac-header", "", "Header containing the HMAC signature (generic scheme)")
+	fs.StringVar(&app.Auth.HMAC.Algorithm, "hmac-algorithm", "sha256", "HMAC algorithm, sha256 or sha512 (generic scheme)")
+	fs.StringVar(&app.Auth.HMAC.Encoding, "hmac-encoding", "hex", "Encoding of the HMAC signature, hex or base64 (generic scheme)")
+	fs.StringVar(&app.Auth.HMAC.Prefix, "hmac-prefix", "", "Prefix of the HMAC signature, e.g. sha256= (generic scheme)")
+	fs.StringVar(&app.Auth.HMAC.TimestampHeader, "hmac-timestamp-header", "", "Header containing the unix timestamp that is signed as <timestamp>.<body> (generic scheme)")
+	fs.DurationVar(&app.Auth.HMAC.Tolerance, "hmac-tolerance", 5*time.Minute, "Maximum age of a signature timestamp")
+	bearerTokens := fs.String("bearer-tokens", "", "Comma separated list of accepted bearer tokens")
+	apiKeys := fs.String("api-keys", "", "Comma separated list of accepted api keys")
+	fs.StringVar(&app.Auth.APIKeyHeader, "api-key-header", "X-API-Key", "Header containing the api key")
+	fs.StringVar(&app.Auth.APIKeyQuery, "api-key-query", "", "Query parameter containing the api key")
+	fs.StringVar(&app.Auth.JWKSFile, "jwks-file", "", "Location of the JWKS file used to validate JWTs")
+	fs.StringVar(&app.Auth.JWTIssuer, "jwt-issuer", "", "Expected issuer of JWTs (optional)")
+	fs.StringVar(&app.Auth.JWTAudience, "jwt-audience", "", "Expected audience of JWTs (optional)")
+	mtlsAllowedSubjects := fs.String("mtls-allowed-subjects", "", "Comma separated list of accepted client certificate common names, all verified certificates are accepted if empty")
+
+	fs.BoolVar(&app.Dedup.Enabled, "dedup", false, "Acknowledge redelivered events without forwarding them again")
+	fs.StringVar(&app.Dedup.Header, "dedup-header", "", "Header containing the delivery id, e.g. X-Delivery-Id (optional)")
+	fs.StringVar(&app.Dedup.Path, "dedup-path", "", "gjson path of the delivery id in the received data (optional)")
+	fs.DurationVar(&app.Dedup.TTL, "dedup-ttl", 24*time.Hour, "Time window in which an event is considered a duplicate")
+	fs.IntVar(&app.Dedup.MaxEntries, "dedup-max-entries", 100000, "Maximum number of remembered delivery ids, the least recently seen ids are evicted first")
+	fs.StringVar(&app.Dedup.File, "dedup-file", "", "File the remembered delivery ids are persisted in (optional)")
+
+	fs.StringVar(&app.Schema.Config, "schema-config", "", "Location of the schema config mapping event types to JSON schema files (optional)")
+	fs.StringVar(&app.Schema.Action, "schema-action", SchemaActionReject, "Handling of events violating their schema, reject or quarantine")
+	fs.StringVar(&app.Schema.QuarantineFile, "schema-quarantine-file", "", "File events violating their schema are appended to in the quarantine action")
+
+	fs.StringVar(&app.Batch.Path, "batch-path", "", "gjson path of the events array in batches, the batch itself is the array if empty")
+	fs.IntVar(&app.Batch.Concurrency, "batch-concurrency", 8, "Number of events of a batch that are processed in parallel")
+
+	fs.Float64Var(&app.Limits.Rate, "rate-limit", 0, "Requests per second accepted of a single source, larger bursts are rejected with 429 (disabled if 0)")
+	fs.IntVar(&app.Limits.Burst, "rate-burst", 0, "Requests of a single source accepted at once, defaults to the rate")
+	fs.StringVar(&app.Limits.Key, "rate-limit-key", limit.KeyIP, "Source of the rate limit, ip or principal")
+	fs.DurationVar(&app.ForwardTimeout, "forward-timeout", 30*time.Second, "Timeout of a single forwarding request, the default of targets without an own timeout")
+	fs.IntVar(&app.Limits.MaxConcurrentForwards, "max-concurrent-forwards", 0, "Maximum number of events forwarded at the same time, further events wait (unlimited if 0)")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	var envErr error
+	fs.VisitAll(func(f *flag.Flag) {
+		if explicit[f.Name] || envErr != nil {
+			return
+		}
+		if value, ok := os.LookupEnv(envName(f.Name)); ok {
+			if envErr = fs.Set(f.Name, value); envErr != nil {
+				envErr = fmt.Errorf("invalid value %q of %s: %s", value, envName(f.Name), envErr.Error())
+				return
+			}
+			explicit[f.Name] = true
+		}
+	})
+	if envErr != nil {
+		return nil, envErr
+	}
+
+	if *configFile != "" {
+		fileConfig, err := readFile(*configFile)
+		if err != nil {
+			return nil, err
+		}
+		//flags and environment override the global settings of the file
+		cfg.merge(fileConfig, explicit)
+	}
+
+	if app.Name != "" {
+		app.Auth.BearerTokens = splitList(*bearerTokens)
+		app.Auth.APIKeys = splitList(*apiKeys)
+		app.Auth.MTLSAllowedSubjects = splitList(*mtlsAllowedSubjects)
+		cfg.Apps = append(cfg.Apps, app)
+	}
+
+	cfg.Limits.applyDefaults()
+	for i := range cfg.Apps {
+		cfg.Apps[i].applyDefaults()
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+//Validate - checks the configuration for missing or conflicting settings
+func (c *Config) Validate() error {
+	if len(c.Apps) == 0 {
+		return fmt.Errorf("Invalid configuration - Missing APP Name")
+	}
+
+	if c.Limits.MaxBodySize < 1 {
+		return fmt.Errorf("Invalid configuration - Maximum body size must be positive")
+	}
+	if c.Limits.Rate < 0 {
+		return fmt.Errorf("Invalid configuration - Global rate limit must not be negative")
+	}
+
+	names := map[string]bool{}
+	dedupFiles := map[string]bool{}
+	for _, app := range c.Apps {
+		if !appNameRegex.MatchString(app.Name) {
+			return fmt.Errorf("Invalid configuration - Invalid APP Name %q", app.Name)
+		}
+		if names[app.Name] {
+			return fmt.Errorf("Invalid configuration - Duplicate APP Name %q", app.Name)
+		}
+		names[app.Name] = true
+
+		//unauthenticated apps have to be configured explicitly
+		if app.Auth.Mode == "" {
+			return fmt.Errorf("Invalid configuration - Missing the auth mode of app %s, set it to none to accept unauthenticated requests", app.Name)
+		}
+
+		if app.EventTypeQuery == "" && app.EventTypeRules == "" {
+			return fmt.Errorf("Invalid configuration - Missing the Event Type Query/Rules of app %s", app.Name)
+		}
+
+		switch app.EventFormat {
+		case "legacy", "structured", "binary":
+		default:
+			return fmt.Errorf("Invalid configuration - Unknown event format %s of app %s", app.EventFormat, app.Name)
+		}
+
+		switch app.Schema.Action {
+		case SchemaActionReject:
+		case SchemaActionQuarantine:
+			if app.Schema.QuarantineFile == "" {
+				return fmt.Errorf("Invalid configuration - Missing the schema quarantine file of app %s", app.Name)
+			}
+		default:
+			return fmt.Errorf("Invalid configuration - Unknown schema action %s of app %s", app.Schema.Action, app.Name)
+		}
+
+		if app.ForwardTimeout < 0 {
+			return fmt.Errorf("Invalid configuration - Forward timeout of app %s must not be negative", app.Name)
+		}
+
+		if app.Batch.Concurrency < 1 {
+			return fmt.Errorf("Invalid configuration - Batch concurrency of app %s must be positive", app.Name)
+		}
+
+		if err := app.Limits.validate(app.Name, app.Auth.Mode); err != nil {
+			return err
+		}
+
+		if err := app.validateRouting(); err != nil {
+			return err
+		}
+
+		if app.Dedup.Enabled && app.Dedup.File != "" {
+			if dedupFiles[app.Dedup.File] {
+				return fmt.Errorf("Invalid configuration - Dedup file %s of app %s is used by another app", app.Dedup.File, app.Name)
+			}
+			dedupFiles[app.Dedup.File] = true
+		}
+	}
+
+	return nil
+}
+
+//readFile - reads the YAML configuration file
+func readFile(file string) (*Config, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading configuration file: %s", err.Error())
+	}
+
+	var fileConfig Config
+	if err := yaml.UnmarshalStrict(data, &fileConfig); err != nil {
+		return nil, fmt.Errorf("error in configuration file %s: %s", file, err.Error())
+	}
+	return &fileConfig, nil
 }
 
-//GlobalConfig contains config
-var GlobalConfig *Config
+//merge - takes the settings of the file unless they are set explicitly
+func (c *Config) merge(file *Config, explicit map[string]bool) {
+	mergeInt(&c.Port, file.Port, explicit["port"])
+	mergeInt(&c.ManagementPort, file.ManagementPort, explicit["management-port"])
+	mergeDuration(&c.ReadTimeout, file.ReadTimeout, explicit["read-timeout"])
+	mergeDuration(&c.WriteTimeout, file.WriteTimeout, explicit["write-timeout"])
+	mergeDuration(&c.IdleTimeout, file.IdleTimeout, explicit["idle-timeout"])
+	mergeDuration(&c.ShutdownTimeout, file.ShutdownTimeout, explicit["shutdown-timeout"])
+	mergeDuration(&c.ReadyCheckInterval, file.ReadyCheckInterval, explicit["ready-check-interval"])
+	mergeString(&c.TLS.CertFile, file.TLS.CertFile, explicit["tls-cert-file"])
+	mergeString(&c.TLS.KeyFile, file.TLS.KeyFile, explicit["tls-key-file"])
+	mergeString(&c.TLS.ClientCAFile, file.TLS.ClientCAFile, explicit["tls-client-ca-file"])
+	mergeString(&c.Outbox.Dir, file.Outbox.Dir, explicit["outbox-dir"])
+	mergeInt(&c.Outbox.Workers, file.Outbox.Workers, explicit["outbox-workers"])
+	mergeInt(&c.Outbox.MaxAttempts, file.Outbox.MaxAttempts, explicit["outbox-max-attempts"])
+	mergeDuration(&c.Outbox.InitialBackoff, file.Outbox.InitialBackoff, explicit["outbox-initial-backoff"])
+	mergeDuration(&c.Outbox.MaxBackoff, file.Outbox.MaxBackoff, explicit["outbox-max-backoff"])
+	mergeString(&c.Capture.Dir, file.Capture.Dir, explicit["capture-dir"])
+	mergeInt64(&c.Capture.MaxFileSize, file.Capture.MaxFileSize, explicit["capture-max-file-size"])
+	mergeInt(&c.Capture.MaxFiles, file.Capture.MaxFiles, explicit["capture-max-files"])
+	mergeInt64(&c.Limits.MaxBodySize, file.Limits.MaxBodySize, explicit["max-body-size"])
+	mergeFloat(&c.Limits.Rate, file.Limits.Rate, explicit["global-rate-limit"])
+	mergeInt(&c.Limits.Burst, file.Limits.Burst, explicit["global-rate-burst"])
+	mergeString(&c.Limits.ClientIPHeader, file.Limits.ClientIPHeader, explicit["client-ip-header"])
 
-//Initialize the configuration
-func init() {
+	c.Apps = append(c.Apps, file.Apps...)
+}
 
-	GlobalConfig = &Config{
-		AppName:         flag.String("app-name", "", "Application Name"),
-		Password:        flag.String("password", "", "Basic Auth Password"),
-		UserName:        flag.String("username", "", "Basic Auth UserName"),
-		EventTypeQuery:  flag.String("event-type-query", "", "The json query based on the get function of https://github.com/tidwall/gjson"),
-		EventPublishURL: flag.String("event-publish-url", "http://event-publish-service.kyma-system.svc.cluster.local:8080/v1/events", "URL to forward incoming events to Kyma Eventing"),
+//applyDefaults - defaults for settings omitted in the configuration file
+func (a *AppConfig) applyDefaults() {
+	if a.EventPublishURL == "" {
+		a.EventPublishURL = DefaultEventPublishURL
+	}
+	if a.EventFormat == "" {
+		a.EventFormat = "legacy"
+	}
+	if a.Auth.Mode == "" && (a.Auth.Username != "" || a.Auth.Password != "") {
+		a.Auth.Mode = auth.ModeBasic
+	}
+	if a.Auth.HMAC.Tolerance == 0 {
+		a.Auth.HMAC.Tolerance = 5 * time.Minute
+	}
+	if a.Auth.APIKeyHeader == "" && a.Auth.APIKeyQuery == "" {
+		a.Auth.APIKeyHeader = "X-API-Key"
+	}
+	if a.Dedup.TTL == 0 {
+		a.Dedup.TTL = 24 * time.Hour
+	}
+	if a.Dedup.MaxEntries == 0 {
+		a.Dedup.MaxEntries = 100000
+	}
+	if a.Schema.Action == "" {
+		a.Schema.Action = SchemaActionReject
 	}
+	if a.Batch.Concurrency == 0 {
+		a.Batch.Concurrency = 8
+	}
+	if a.Limits.Key == "" {
+		a.Limits.Key = limit.KeyIP
+	}
+	a.Limits.Burst = defaultBurst(a.Limits.Rate, a.Limits.Burst)
+	if a.ForwardTimeout == 0 {
+		a.ForwardTimeout = 30 * time.Second
+	}
+	for i := range a.Targets {
+		target := &a.Targets[i]
+		if target.Type == "" {
+			target.Type = TargetKyma
+		}
+		if target.Timeout == 0 {
+			target.Timeout = a.ForwardTimeout
+		}
+		if target.Format == "" {
+			//plain endpoints receive the event data as it is
+			target.Format = "binary"
+			if target.Type == TargetKyma {
+				target.Format = a.EventFormat
+			}
+		}
+	}
+}
+
+//validateRouting - checks the targets of the app and that routes only refer to them
+func (a *AppConfig) validateRouting() error {
+	targets := map[string]bool{}
+	for _, target := range a.Targets {
+		if !appNameRegex.MatchString(target.Name) {
+			return fmt.Errorf("Invalid configuration - Invalid target name %q of app %s", target.Name, a.Name)
+		}
+		if targets[target.Name] {
+			return fmt.Errorf("Invalid configuration - Duplicate target name %q of app %s", target.Name, a.Name)
+		}
+		targets[target.Name] = true
 
-	flag.Parse()
+		switch target.Type {
+		case TargetKyma, TargetHTTP:
+			if target.URL == "" {
+				return fmt.Errorf("Invalid configuration - Missing the url of target %s of app %s", target.Name, a.Name)
+			}
+			switch target.Format {
+			case "legacy", "structured", "binary":
+			default:
+				return fmt.Errorf("Invalid configuration - Unknown event format %s of target %s of app %s", target.Format, target.Name, a.Name)
+			}
+		case TargetFile:
+			if target.File == "" {
+				return fmt.Errorf("Invalid configuration - Missing the file of target %s of app %s", target.Name, a.Name)
+			}
+		default:
+			return fmt.Errorf("Invalid configuration - Unknown type %s of target %s of app %s", target.Type, target.Name, a.Name)
+		}
 
-	if *GlobalConfig.AppName == "" || *GlobalConfig.EventTypeQuery == "" {
-		log.Fatalf("Invalid configuration - Missing APP Name or the Event Type Query")
+		if target.MaxConcurrentForwards < 0 {
+			return fmt.Errorf("Invalid configuration - Maximum concurrent forwards of target %s of app %s must not be negative", target.Name, a.Name)
+		}
 	}
 
+	if len(a.Routes) > 0 && len(a.Targets) == 0 {
+		return fmt.Errorf("Invalid configuration - Routes of app %s require targets", a.Name)
+	}
+	for i, route := range a.Routes {
+		if len(route.Targets) == 0 {
+			return fmt.Errorf("Invalid configuration - Route %d of app %s has no targets", i, a.Name)
+		}
+		for _, target := range route.Targets {
+			if !targets[target] {
+				return fmt.Errorf("Invalid configuration - Route %d of app %s refers to the unknown target %s", i, a.Name, target)
+			}
+		}
+		for _, pattern := range route.EventTypes {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("Invalid configuration - Invalid event type pattern %q of route %d of app %s", pattern, i, a.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+//applyDefaults - the burst defaults to the rate
+func (l *LimitsConfig) applyDefaults() {
+	l.Burst = defaultBurst(l.Rate, l.Burst)
+}
+
+//validate - checks the limits of the app
+func (l *AppLimits) validate(appName string, authMode string) error {
+	if l.MaxBodySize < 0 {
+		return fmt.Errorf("Invalid configuration - Maximum body size of app %s must not be negative", appName)
+	}
+	if l.Rate < 0 {
+		return fmt.Errorf("Invalid configuration - Rate limit of app %s must not be negative", appName)
+	}
+	if l.MaxConcurrentForwards < 0 {
+		return fmt.Errorf("Invalid configuration - Maximum concurrent forwards of app %s must not be negative", appName)
+	}
+
+	switch l.Key {
+	case limit.KeyIP:
+	case limit.KeyPrincipal:
+		//all unauthenticated requests share the same principal
+		if authMode == auth.ModeNone {
+			return fmt.Errorf("Invalid configuration - Rate limit key principal of app %s requires authentication", appName)
+		}
+	default:
+		return fmt.Errorf("Invalid configuration - Unknown rate limit key %s of app %s", l.Key, appName)
+	}
+	return nil
+}
+
+//defaultBurst - at least one request has to fit into a burst
+func defaultBurst(rate float64, burst int) int {
+	if rate > 0 && burst < 1 {
+		return int(math.Max(1, math.Ceil(rate)))
+	}
+	return burst
+}
+
+//envName - app-name is read from GEN_EVENT_GW_APP_NAME
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.Replace(flagName, "-", "_", -1))
+}
+
+//splitList - splits a comma separated list, entries are trimmed and empty entries are dropped
+func splitList(list string) []string {
+	var result []string
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+func mergeString(target *string, value string, explicit bool) {
+	if !explicit && value != "" {
+		*target = value
+	}
+}
+
+func mergeInt(target *int, value int, explicit bool) {
+	if !explicit && value != 0 {
+		*target = value
+	}
+}
+
+func mergeInt64(target *int64, value int64, explicit bool) {
+	if !explicit && value != 0 {
+		*target = value
+	}
+}
+
+func mergeFloat(target *float64, value float64, explicit bool) {
+	if !explicit && value != 0 {
+		*target = value
+	}
+}
+
+func mergeDuration(target *time.Duration, value time.Duration, explicit bool) {
+	if !explicit && value != 0 {
+		*target = value
+	}
 }
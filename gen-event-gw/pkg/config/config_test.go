@@ -0,0 +1,149 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/auth"
+)
+
+func TestLoadFlags(t *testing.T) {
+
+	cfg, err := Load([]string{"-app-name", "shop", "-event-type-query", "type", "-username", "user", "-password", "pass", "-bearer-tokens", "a, b,", "-mtls-allowed-subjects", "CN=a, CN=b"})
+	if err != nil {
+		t.Fatalf("Loading flags failed: %s", err.Error())
+	}
+
+	if len(cfg.Apps) != 1 {
+		t.Fatalf("Expected 1 app, got %d", len(cfg.Apps))
+	}
+	app := cfg.Apps[0]
+	if app.Name != "shop" || app.EventPublishURL != DefaultEventPublishURL || app.EventFormat != "legacy" {
+		t.Errorf("Unexpected app config %+v", app)
+	}
+	if app.Auth.Mode != auth.ModeBasic {
+		t.Errorf("Expected basic auth, got %s", app.Auth.Mode)
+	}
+	if len(app.Auth.BearerTokens) != 2 || app.Auth.BearerTokens[1] != "b" {
+		t.Errorf("Expected 2 bearer tokens, got %v", app.Auth.BearerTokens)
+	}
+	if len(app.Auth.MTLSAllowedSubjects) != 2 || app.Auth.MTLSAllowedSubjects[1] != "CN=b" {
+		t.Errorf("Expected trimmed subjects, got %q", app.Auth.MTLSAllowedSubjects)
+	}
+	if cfg.Port != 8080 || cfg.ManagementPort != 8081 {
+		t.Errorf("Unexpected ports %d, %d", cfg.Port, cfg.ManagementPort)
+	}
+}
+
+func TestLoadEnvironment(t *testing.T) {
+
+	os.Setenv("GEN_EVENT_GW_APP_NAME", "env-app")
+	os.Setenv("GEN_EVENT_GW_EVENT_TYPE_QUERY", "type")
+	os.Setenv("GEN_EVENT_GW_PORT", "9000")
+	os.Setenv("GEN_EVENT_GW_AUTH_MODE", "none")
+	defer os.Unsetenv("GEN_EVENT_GW_APP_NAME")
+	defer os.Unsetenv("GEN_EVENT_GW_AUTH_MODE")
+	defer os.Unsetenv("GEN_EVENT_GW_EVENT_TYPE_QUERY")
+	defer os.Unsetenv("GEN_EVENT_GW_PORT")
+
+	cfg, err := Load([]string{"-port", "9001"})
+	if err != nil {
+		t.Fatalf("Loading environment failed: %s", err.Error())
+	}
+
+	if len(cfg.Apps) != 1 || cfg.Apps[0].Name != "env-app" {
+		t.Errorf("Expected app env-app, got %+v", cfg.Apps)
+	}
+	if cfg.Port != 9001 {
+		t.Errorf("Flags should take precedence over environment, got port %d", cfg.Port)
+	}
+
+	os.Setenv("GEN_EVENT_GW_PORT", "invalid")
+	if _, err := Load(nil); err == nil {
+		t.Errorf("Loading an invalid environment value should fail")
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+
+	cfg, err := Load([]string{"-config", "testdata/config.yaml", "-outbox-workers", "8"})
+	if err != nil {
+		t.Fatalf("Loading config file failed: %s", err.Error())
+	}
+
+	if cfg.Port != 9080 || cfg.ManagementPort != 8081 || cfg.ShutdownTimeout != 10*time.Second || cfg.ReadTimeout != 30*time.Second {
+		t.Errorf("Unexpected ports %d, %d", cfg.Port, cfg.ManagementPort)
+	}
+	if cfg.Outbox.Dir != "/var/lib/gen-event-gw" || cfg.Outbox.Workers != 8 || cfg.Outbox.InitialBackoff != 2*time.Second || cfg.Outbox.MaxAttempts != 10 {
+		t.Errorf("Unexpected outbox config %+v", cfg.Outbox)
+	}
+	if cfg.Limits.Rate != 100 || cfg.Limits.Burst != 100 || cfg.Limits.MaxBodySize != 10*1024*1024 || cfg.Limits.ClientIPHeader != "X-Forwarded-For" {
+		t.Errorf("Unexpected limits %+v", cfg.Limits)
+	}
+	if len(cfg.Apps) != 2 {
+		t.Fatalf("Expected 2 apps, got %d", len(cfg.Apps))
+	}
+
+	shop := cfg.Apps[0]
+	if shop.Auth.Mode != auth.ModeHMAC || shop.Auth.HMAC.Scheme != auth.SchemeShopify || shop.Auth.HMAC.Tolerance != 5*time.Minute {
+		t.Errorf("Unexpected auth config of shop %+v", shop.Auth)
+	}
+	if shop.EventFormat != "structured" || shop.EventPublishURL != DefaultEventPublishURL {
+		t.Errorf("Unexpected config of shop %+v", shop)
+	}
+	if shop.Limits.Rate != 2.5 || shop.Limits.Burst != 3 || shop.Limits.Key != "principal" || shop.Limits.MaxConcurrentForwards != 16 {
+		t.Errorf("Unexpected limits of shop %+v", shop.Limits)
+	}
+	if len(shop.Targets) != 3 || len(shop.Routes) != 2 {
+		t.Fatalf("Expected 3 targets and 2 routes of shop, got %+v, %+v", shop.Targets, shop.Routes)
+	}
+	if orders := shop.Targets[0]; orders.Type != TargetKyma || orders.Format != "structured" || orders.Timeout != 30*time.Second {
+		t.Errorf("Unexpected defaults of target %+v", orders)
+	}
+	if erp := shop.Targets[1]; erp.Format != "binary" || erp.Timeout != 5*time.Second || erp.Auth.BearerToken != "erp-token" {
+		t.Errorf("Unexpected config of target %+v", erp)
+	}
+
+	crm := cfg.Apps[1]
+	if crm.Auth.Mode != auth.ModeBasic || crm.EventFormat != "legacy" || crm.EventPublishURL != "http://localhost:9999/v1/events" {
+		t.Errorf("Unexpected config of crm %+v", crm)
+	}
+	if !crm.Dedup.Enabled || crm.Dedup.Header != "X-Delivery-Id" || crm.Dedup.TTL != 24*time.Hour || crm.Dedup.MaxEntries != 100000 {
+		t.Errorf("Unexpected dedup config of crm %+v", crm.Dedup)
+	}
+	if crm.Limits.Rate != 0 || crm.Limits.Burst != 0 || crm.Limits.Key != "ip" {
+		t.Errorf("Unexpected limits of crm %+v", crm.Limits)
+	}
+
+	_, err = Load([]string{"-config", "testdata/config_invalid.yaml"})
+	if err == nil {
+		t.Errorf("Loading a config file with unknown settings should fail")
+	}
+}
+
+func TestValidate(t *testing.T) {
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"missing app", []string{}},
+		{"invalid app name", []string{"-app-name", "shop/orders", "-event-type-query", "type", "-auth-mode", "none"}},
+		{"missing event type query", []string{"-app-name", "shop", "-auth-mode", "none"}},
+		{"unknown event format", []string{"-app-name", "shop", "-event-type-query", "type", "-event-format", "xml", "-auth-mode", "none"}},
+		{"zero body size", []string{"-app-name", "shop", "-event-type-query", "type", "-max-body-size", "0", "-auth-mode", "none"}},
+		{"negative rate limit", []string{"-app-name", "shop", "-event-type-query", "type", "-rate-limit", "-1", "-auth-mode", "none"}},
+		{"unknown rate limit key", []string{"-app-name", "shop", "-event-type-query", "type", "-rate-limit-key", "host", "-auth-mode", "none"}},
+		{"missing auth mode", []string{"-app-name", "shop", "-event-type-query", "type"}},
+		{"principal without auth", []string{"-app-name", "shop", "-event-type-query", "type", "-auth-mode", "none", "-rate-limit", "5", "-rate-limit-key", "principal"}},
+		{"unknown route target", []string{"-config", "testdata/config_routing_invalid.yaml"}},
+		{"duplicate app name", []string{"-config", "testdata/config.yaml", "-app-name", "shop", "-event-type-query", "type", "-auth-mode", "none"}},
+	}
+
+	for _, test := range tests {
+		if _, err := Load(test.args); err == nil {
+			t.Errorf("%s: expected validation error", test.name)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/url"
+	"strings"
+)
+
+const (
+	formContentType      = "application/x-www-form-urlencoded"
+	multipartContentType = "multipart/form-data"
+)
+
+//ToJSON - converts the request body into a canonical JSON document based on the content type
+//JSON and unknown content types are passed through unchanged
+func ToJSON(contentType string, body []byte) ([]byte, error) {
+	//senders and tools like curl label JSON bodies with the form content type
+	if contentType == "" || isJSONDocument(body) {
+		return body, nil
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("invalid content type %q: %s", contentType, err.Error())
+	}
+
+	switch {
+	case isXML(mediaType):
+		return xmlToJSON(body)
+	case mediaType == formContentType:
+		return formToJSON(body)
+	case mediaType == multipartContentType:
+		return multipartToJSON(body, params["boundary"])
+	default:
+		return body, nil
+	}
+}
+
+func isJSONDocument(body []byte) bool {
+	trimmed := bytes.TrimSpace(body)
+	return len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed)
+}
+
+func isXML(mediaType string) bool {
+	return mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml")
+}
+
+func isJSON(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+//formToJSON - single values are mapped to strings, repeated keys to arrays
+func formToJSON(body []byte) ([]byte, error) {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing form data: %s", err.Error())
+	}
+
+	result := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		if len(value) == 1 {
+			result[key] = value[0]
+		} else {
+			result[key] = value
+		}
+	}
+
+	return json.Marshal(result)
+}
+
+//multipartFile - representation of an uploaded file
+type multipartFile struct {
+	FileName    string      `json:"filename"`
+	ContentType string      `json:"contentType,omitempty"`
+	Content     interface{} `json:"content"`
+}
+
+//multipartToJSON - fields are mapped to strings, files to objects, JSON and XML parts are embedded as JSON
+func multipartToJSON(body []byte, boundary string) ([]byte, error) {
+	if boundary == "" {
+		return nil, fmt.Errorf("missing boundary in multipart content type")
+	}
+
+	result := newObject()
+	reader := multipart.NewReader(bytes.NewReader(body), boundary)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading multipart data: %s", err.Error())
+		}
+
+		value, err := partValue(part)
+		if err != nil {
+			return nil, err
+		}
+		result.add(part.FormName(), value)
+	}
+
+	return json.Marshal(result)
+}
+
+func partValue(part *multipart.Part) (interface{}, error) {
+	content, err := ioutil.ReadAll(part)
+	if err != nil {
+		return nil, fmt.Errorf("error reading multipart field %q: %s", part.FormName(), err.Error())
+	}
+
+	partContentType := part.Header.Get("Content-Type")
+	var value interface{}
+	if mediaType, _, err := mime.ParseMediaType(partContentType); err == nil && (isJSON(mediaType) || isXML(mediaType)) {
+		converted, err := ToJSON(partContentType, content)
+		if err != nil {
+			return nil, fmt.Errorf("error converting multipart field %q: %s", part.FormName(), err.Error())
+		}
+		if !json.Valid(converted) {
+			return nil, fmt.Errorf("multipart field %q does not contain valid json", part.FormName())
+		}
+		value = json.RawMessage(converted)
+	}
+
+	if part.FileName() == "" {
+		if value == nil {
+			value = string(content)
+		}
+		return value, nil
+	}
+
+	if value == nil {
+		value = base64.StdEncoding.EncodeToString(content)
+	}
+	return &multipartFile{
+		FileName:    part.FileName(),
+		ContentType: partContentType,
+		Content:     value,
+	}, nil
+}
@@ -0,0 +1,107 @@
+package convert
+
+import (
+	"bytes"
+	"mime/multipart"
+	"testing"
+
+	"github.com/tidwall/gjson"
+)
+
+func TestToJSON_PassThrough(t *testing.T) {
+
+	body := []byte(`{"b":1,"a":2}`)
+
+	for _, contentType := range []string{"", "application/json", "application/json; charset=utf-8", "text/plain"} {
+		result, err := ToJSON(contentType, body)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", contentType, err.Error())
+		}
+		if string(result) != string(body) {
+			t.Errorf("%q: body should be passed through, got %s", contentType, string(result))
+		}
+	}
+
+	result, err := ToJSON("application/x-www-form-urlencoded", body)
+	if err != nil || string(result) != string(body) {
+		t.Errorf("json labelled as form should be passed through, got %s", string(result))
+	}
+
+	_, err = ToJSON("application/", []byte("a=b"))
+	if err == nil {
+		t.Error("invalid content type should be rejected")
+	}
+}
+
+func TestToJSON_XML(t *testing.T) {
+
+	body := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<WorkOrder xmlns="urn:fieldglass:workorder" xmlns:fg="urn:fieldglass">
+	<ID>WO-1</ID>
+	<Status code="A">Approved</Status>
+	<Line>1</Line>
+	<Line>2</Line>
+	<Empty/>
+</WorkOrder>`)
+
+	result, err := ToJSON("application/xml", body)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	expected := `{"WorkOrder":{"@xmlns":"urn:fieldglass:workorder","@xmlns:fg":"urn:fieldglass","ID":"WO-1",` +
+		`"Status":{"@code":"A","#text":"Approved"},"Line":["1","2"],"Empty":""}}`
+	if string(result) != expected {
+		t.Errorf("unexpected conversion result:\n%s\nexpected:\n%s", string(result), expected)
+	}
+
+	if gjson.Get(string(result), "*.@xmlns").String() != "urn:fieldglass:workorder" {
+		t.Error("namespace should be accessible with the fieldglass event type query")
+	}
+
+	_, err = ToJSON("text/xml", []byte(`<Open><Unclosed></Open>`))
+	if err == nil {
+		t.Error("invalid xml should be rejected")
+	}
+}
+
+func TestToJSON_Form(t *testing.T) {
+
+	result, err := ToJSON("application/x-www-form-urlencoded", []byte("status=created&id=1&tag=a&tag=b"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	expected := `{"id":"1","status":"created","tag":["a","b"]}`
+	if string(result) != expected {
+		t.Errorf("unexpected conversion result %s, expected %s", string(result), expected)
+	}
+}
+
+func TestToJSON_Multipart(t *testing.T) {
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	_ = writer.WriteField("status", "created")
+	file, _ := writer.CreateFormFile("attachment", "note.txt")
+	_, _ = file.Write([]byte("hello"))
+	_ = writer.Close()
+
+	result, err := ToJSON(writer.FormDataContentType(), body.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	parsed := gjson.ParseBytes(result)
+	if parsed.Get("status").String() != "created" {
+		t.Errorf("field not converted: %s", string(result))
+	}
+	if parsed.Get("attachment.filename").String() != "note.txt" || parsed.Get("attachment.content").String() != "aGVsbG8=" {
+		t.Errorf("file not converted: %s", string(result))
+	}
+
+	_, err = ToJSON("multipart/form-data", body.Bytes())
+	if err == nil {
+		t.Error("missing boundary should be rejected")
+	}
+}
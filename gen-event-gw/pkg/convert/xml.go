@@ -0,0 +1,134 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	attributePrefix = "@"
+	textKey         = "#text"
+)
+
+//object - JSON object keeping the order of the XML document
+type object struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newObject() *object {
+	return &object{values: map[string]interface{}{}}
+}
+
+//add - repeated keys are collected into an array
+func (o *object) add(key string, value interface{}) {
+	existing, ok := o.values[key]
+	if !ok {
+		o.keys = append(o.keys, key)
+		o.values[key] = value
+		return
+	}
+
+	if list, isList := existing.([]interface{}); isList {
+		o.values[key] = append(list, value)
+	} else {
+		o.values[key] = []interface{}{existing, value}
+	}
+}
+
+//MarshalJSON writes the keys in insertion order
+func (o *object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		keyBytes, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		valueBytes, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(keyBytes)
+		buf.WriteByte(':')
+		buf.Write(valueBytes)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+//xmlToJSON - elements become keys, attributes are prefixed with "@" and mixed text is stored as "#text"
+//elements without attributes and children are mapped to their text
+func xmlToJSON(body []byte) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, fmt.Errorf("xml document does not contain a root element")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error parsing xml: %s", err.Error())
+		}
+
+		if start, ok := token.(xml.StartElement); ok {
+			value, err := convertElement(decoder, start)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing xml: %s", err.Error())
+			}
+			root := newObject()
+			root.add(start.Name.Local, value)
+			return json.Marshal(root)
+		}
+	}
+}
+
+func convertElement(decoder *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	element := newObject()
+	for _, attr := range start.Attr {
+		element.add(attributePrefix+attributeName(attr.Name), attr.Value)
+	}
+
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			child, err := convertElement(decoder, t)
+			if err != nil {
+				return nil, err
+			}
+			element.add(t.Name.Local, child)
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			content := strings.TrimSpace(text.String())
+			if len(element.keys) == 0 {
+				return content, nil
+			}
+			if content != "" {
+				element.add(textKey, content)
+			}
+			return element, nil
+		}
+	}
+}
+
+//attributeName - keeps namespace declarations recognizable (xmlns, xmlns:prefix)
+func attributeName(name xml.Name) string {
+	if name.Space == "xmlns" {
+		return "xmlns:" + name.Local
+	}
+	return name.Local
+}
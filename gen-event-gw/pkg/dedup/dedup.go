@@ -0,0 +1,246 @@
+package dedup
+
+import (
+	"bufio"
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//Config - settings of the duplicate detection store
+type Config struct {
+	TTL        time.Duration
+	MaxEntries int
+	File       string
+}
+
+//record - a key is a duplicate until it expires, removed keys are persisted with a zero expiry
+type record struct {
+	Key     string    `json:"key"`
+	Expires time.Time `json:"expires"`
+}
+
+//Store - keys seen within the TTL, the least recently seen keys are evicted if the store is full
+//If a file is configured every change is appended to it, so the seen keys survive a restart
+type Store struct {
+	config Config
+
+	lock    sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+
+	file    *os.File
+	written int
+
+	now func() time.Time
+}
+
+//New - creates the store and loads the keys of the file if configured
+func New(config Config) (*Store, error) {
+	if config.TTL <= 0 {
+		return nil, fmt.Errorf("dedup ttl must be positive, got %s", config.TTL)
+	}
+	if config.MaxEntries < 1 {
+		return nil, fmt.Errorf("dedup store needs room for at least one entry, got %d", config.MaxEntries)
+	}
+
+	s := &Store{
+		config:  config,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		now:     time.Now,
+	}
+
+	if config.File == "" {
+		return s, nil
+	}
+
+	if err := ensureDir(config.File); err != nil {
+		return nil, fmt.Errorf("error creating dedup directory: %s", err.Error())
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	if err := s.compact(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+//Add - records the key, false if the key has already been seen within the TTL
+func (s *Store) Add(key string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.now()
+	if element, ok := s.entries[key]; ok {
+		if element.Value.(*record).Expires.After(now) {
+			s.order.MoveToFront(element)
+			return false
+		}
+		s.remove(element)
+	}
+
+	r := &record{Key: key, Expires: now.Add(s.config.TTL)}
+	s.insert(r)
+	s.persist(r)
+	return true
+}
+
+//Remove - forgets the key, e.g. if the event could not be processed and the sender should be able to retry
+func (s *Store) Remove(key string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	element, ok := s.entries[key]
+	if !ok {
+		return
+	}
+	s.remove(element)
+	s.persist(&record{Key: key})
+}
+
+//Len - number of keys in the store, including expired keys that have not been evicted yet
+func (s *Store) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.order.Len()
+}
+
+//Close - closes the file of the store
+func (s *Store) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+func (s *Store) insert(r *record) {
+	s.entries[r.Key] = s.order.PushFront(r)
+	for s.order.Len() > s.config.MaxEntries {
+		s.remove(s.order.Back())
+	}
+}
+
+func (s *Store) remove(element *list.Element) {
+	s.order.Remove(element)
+	delete(s.entries, element.Value.(*record).Key)
+}
+
+//persist - appends the change to the file, the file is compacted once it outgrows the store
+func (s *Store) persist(r *record) {
+	if s.file == nil {
+		return
+	}
+
+	line, err := json.Marshal(r)
+	if err != nil {
+		log.Printf("Dedup key %s could not be persisted - %s", r.Key, err.Error())
+		return
+	}
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		log.Printf("Dedup key %s could not be persisted - %s", r.Key, err.Error())
+		return
+	}
+
+	s.written++
+	if s.written > 2*s.config.MaxEntries {
+		if err := s.compact(); err != nil {
+			log.Printf("Dedup file could not be compacted - %s", err.Error())
+		}
+	}
+}
+
+//load - replays the changes of the file, expired keys are skipped
+func (s *Store) load() error {
+	file, err := os.Open(s.config.File)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading dedup file: %s", err.Error())
+	}
+	defer file.Close()
+
+	now := s.now()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var r record
+		//a partially written last line is ignored
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue
+		}
+
+		if element, ok := s.entries[r.Key]; ok {
+			s.remove(element)
+		}
+		if r.Expires.After(now) {
+			s.insert(&record{Key: r.Key, Expires: r.Expires})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading dedup file: %s", err.Error())
+	}
+	return nil
+}
+
+//compact - replaces the file with the current keys, the least recently seen keys first
+func (s *Store) compact() error {
+	tmpFile := s.config.File + ".tmp"
+	file, err := os.OpenFile(tmpFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("error writing dedup file: %s", err.Error())
+	}
+
+	writer := bufio.NewWriter(file)
+	for element := s.order.Back(); element != nil; element = element.Prev() {
+		line, err := json.Marshal(element.Value)
+		if err != nil {
+			file.Close()
+			return err
+		}
+		writer.Write(append(line, '\n'))
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("error writing dedup file: %s", err.Error())
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("error writing dedup file: %s", err.Error())
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error writing dedup file: %s", err.Error())
+	}
+
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+	if err := os.Rename(tmpFile, s.config.File); err != nil {
+		return fmt.Errorf("error writing dedup file: %s", err.Error())
+	}
+
+	s.file, err = os.OpenFile(s.config.File, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("error opening dedup file: %s", err.Error())
+	}
+	s.written = s.order.Len()
+	return nil
+}
+
+func ensureDir(file string) error {
+	return os.MkdirAll(filepath.Dir(file), 0700)
+}
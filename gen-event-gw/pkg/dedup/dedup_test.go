@@ -0,0 +1,97 @@
+package dedup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+
+	_, err := New(Config{TTL: 0, MaxEntries: 1})
+	if err == nil {
+		t.Error("missing ttl should be rejected")
+	}
+
+	_, err = New(Config{TTL: time.Minute, MaxEntries: 0})
+	if err == nil {
+		t.Error("store without room should be rejected")
+	}
+}
+
+func TestStore_Add(t *testing.T) {
+
+	s, err := New(Config{TTL: time.Minute, MaxEntries: 2})
+	if err != nil {
+		t.Fatalf("store creation failed: %s", err.Error())
+	}
+
+	now := time.Now()
+	s.now = func() time.Time { return now }
+
+	if !s.Add("a") || !s.Add("b") {
+		t.Fatal("new keys should be accepted")
+	}
+	if s.Add("a") {
+		t.Error("key a should be a duplicate")
+	}
+
+	//b is the least recently seen key and evicted
+	s.Add("c")
+	if !s.Add("b") {
+		t.Error("evicted key b should be accepted again")
+	}
+	if s.Len() != 2 {
+		t.Errorf("expected 2 entries, got %d", s.Len())
+	}
+
+	s.Remove("b")
+	if !s.Add("b") {
+		t.Error("removed key b should be accepted again")
+	}
+
+	now = now.Add(time.Minute)
+	if !s.Add("b") {
+		t.Error("expired key b should be accepted again")
+	}
+}
+
+func TestStore_File(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "dedup")
+	defer os.RemoveAll(dir)
+
+	config := Config{TTL: time.Minute, MaxEntries: 3, File: filepath.Join(dir, "keys", "dedup.ndjson")}
+
+	s, err := New(config)
+	if err != nil {
+		t.Fatalf("store creation failed: %s", err.Error())
+	}
+	for _, key := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		s.Add(key)
+	}
+	s.Remove("f")
+	s.Close()
+
+	s, err = New(config)
+	if err != nil {
+		t.Fatalf("store creation failed: %s", err.Error())
+	}
+
+	if s.Len() != 2 {
+		t.Errorf("expected 2 entries, got %d", s.Len())
+	}
+	if s.Add("e") || s.Add("g") {
+		t.Error("persisted keys should be duplicates")
+	}
+	if !s.Add("f") || !s.Add("a") {
+		t.Error("removed and evicted keys should be accepted")
+	}
+
+	s.now = func() time.Time { return time.Now().Add(time.Hour) }
+	if !s.Add("g") {
+		t.Error("expired key g should be accepted")
+	}
+	s.Close()
+}
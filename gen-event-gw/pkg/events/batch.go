@@ -0,0 +1,232 @@
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/convert"
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/metrics"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+)
+
+//BatchItemResult - outcome of a single event of a batch
+type BatchItemResult struct {
+	Index int `json:"index"`
+	*Result
+}
+
+//BatchReport - per event results of a batch
+type BatchReport struct {
+	Total     int               `json:"total"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Results   []BatchItemResult `json:"results"`
+}
+
+//BatchHandler - route to handle batches of events, sent as JSON array or as NDJSON stream
+//Answers with 200 if all events succeeded and 207 with the per event results otherwise
+func (k *KymaEventProcesser) BatchHandler(w http.ResponseWriter, r *http.Request) {
+
+	reqBody, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Println(err)
+		metrics.EventsRejected.WithLabelValues(metrics.ReasonReadError).Inc()
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "Could not read data")
+		return
+	}
+
+	items, err := k.splitBatch(r.Header.Get("Content-Type"), reqBody)
+	if err != nil {
+		log.Println(err)
+		metrics.EventsRejected.WithLabelValues(metrics.ReasonConversion).Inc()
+		result := &Result{Status: http.StatusBadRequest, Message: fmt.Sprintf("Could not split batch - %s", err.Error())}
+		k.record(r, reqBody, true, result)
+		w.WriteHeader(result.Status)
+		fmt.Fprintf(w, "%s", result.Message)
+		return
+	}
+	log.Printf("Received batch of %d events", len(items))
+
+	var deliveryID string
+	if k.dedup != nil {
+		deliveryID = k.dedup.deliveryID(r)
+	}
+
+	report := k.processBatch(r.Context(), deliveryID, items)
+
+	status := http.StatusOK
+	if report.Failed > 0 {
+		status = http.StatusMultiStatus
+	}
+	k.record(r, reqBody, true, &Result{
+		Status:  status,
+		Message: fmt.Sprintf("%d of %d events succeeded", report.Succeeded, report.Total),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+//processBatch - processes the events with bounded parallelism, the results keep the order of the batch
+//Once the context is done no further event is started and the events that have not finished are reported
+//as unavailable, so the report is written before the server closes the connection
+func (k *KymaEventProcesser) processBatch(ctx context.Context, deliveryID string, items [][]byte) *BatchReport {
+	report := &BatchReport{
+		Total:   len(items),
+		Results: make([]BatchItemResult, len(items)),
+	}
+
+	metrics.EventsReceived.Add(float64(len(items)))
+
+	var lock sync.Mutex
+	results := make([]*Result, len(items))
+
+	semaphore := make(chan struct{}, k.batchConcurrency)
+	var wg sync.WaitGroup
+	started := 0
+	for i, item := range items {
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		started++
+
+		//the delivery id of a batch is combined with the position of the event
+		itemDeliveryID := ""
+		if deliveryID != "" {
+			itemDeliveryID = deliveryID + "/" + strconv.Itoa(i)
+		}
+
+		wg.Add(1)
+		go func(i int, item []byte) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			var result *Result
+			if !json.Valid(item) {
+				metrics.EventsRejected.WithLabelValues(metrics.ReasonConversion).Inc()
+				result = &Result{Status: http.StatusBadRequest, Message: "Invalid json"}
+			} else {
+				result = k.processEvent(itemDeliveryID, item)
+				result.Message = strings.TrimSpace(result.Message)
+			}
+
+			lock.Lock()
+			results[i] = result
+			lock.Unlock()
+		}(i, item)
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-ctx.Done():
+		log.Printf("Batch deadline exceeded, %d of %d events were started", started, len(items))
+	}
+
+	lock.Lock()
+	for i, result := range results {
+		if result == nil {
+			result = unfinishedResult(i < started)
+		}
+		report.Results[i] = BatchItemResult{Index: i, Result: result}
+	}
+	lock.Unlock()
+
+	for _, result := range report.Results {
+		if result.Status >= http.StatusOK && result.Status < http.StatusMultipleChoices {
+			report.Succeeded++
+		} else {
+			report.Failed++
+		}
+	}
+
+	return report
+}
+
+//unfinishedResult - result of an event that was not processed before the batch deadline, the sender may retry it
+//An event that was started may still be forwarded, duplicate detection acknowledges its retry
+func unfinishedResult(started bool) *Result {
+	if started {
+		return &Result{Status: http.StatusServiceUnavailable, Message: "Event was not processed before the batch deadline, it may still be forwarded"}
+	}
+	return &Result{Status: http.StatusServiceUnavailable, Message: "Event was not processed before the batch deadline"}
+}
+
+//splitBatch - NDJSON is split by lines, other content is converted to JSON and split by the configured path
+func (k *KymaEventProcesser) splitBatch(contentType string, body []byte) ([][]byte, error) {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && isNDJSON(mediaType) {
+		return splitLines(body)
+	}
+
+	body, err := convert.ToJSON(contentType, body)
+	if err != nil {
+		return nil, err
+	}
+
+	events := gjson.ParseBytes(body)
+	if k.batchPath != "" {
+		events = gjson.GetBytes(body, k.batchPath)
+	}
+	if !events.IsArray() {
+		return nil, fmt.Errorf("batch does not contain an array of events")
+	}
+
+	var items [][]byte
+	events.ForEach(func(_, value gjson.Result) bool {
+		items = append(items, []byte(value.Raw))
+		return true
+	})
+	if len(items) == 0 {
+		return nil, fmt.Errorf("batch does not contain any event")
+	}
+	return items, nil
+}
+
+func splitLines(body []byte) ([][]byte, error) {
+	var items [][]byte
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 64*1024), len(body)+1)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		items = append(items, append([]byte(nil), line...))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("batch does not contain any event")
+	}
+	return items, nil
+}
+
+func isNDJSON(mediaType string) bool {
+	switch mediaType {
+	case "application/x-ndjson", "application/ndjson", "application/jsonl", "application/x-jsonlines":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/config"
+)
+
+func newTestProcesser(t *testing.T, publishURL string, batchPath string) *KymaEventProcesser {
+	k, err := NewKymaEventProcesser(&config.AppConfig{
+		Name:            "shop",
+		EventTypeQuery:  "type",
+		EventPublishURL: publishURL,
+		EventFormat:     "legacy",
+		Batch:           config.BatchConfig{Path: batchPath, Concurrency: 2},
+	}, nil, nil)
+	if err != nil {
+		t.Fatalf("processer creation failed: %s", err.Error())
+	}
+	return k
+}
+
+func TestSplitBatch(t *testing.T) {
+
+	tests := []struct {
+		name        string
+		batchPath   string
+		contentType string
+		body        string
+		items       int
+	}{
+		{"array", "", "application/json", `[{"type":"a"},{"type":"b"}]`, 2},
+		{"wrapper", "events", "application/json", `{"events":[{"type":"a"},{"type":"b"},{"type":"c"}]}`, 3},
+		{"ndjson", "", "application/x-ndjson", "{\"type\":\"a\"}\n\n{\"type\":\"b\"}\n", 2},
+		{"no array", "", "application/json", `{"type":"a"}`, 0},
+		{"empty array", "", "application/json", `[]`, 0},
+	}
+
+	for _, test := range tests {
+		k := newTestProcesser(t, "http://localhost", test.batchPath)
+		items, err := k.splitBatch(test.contentType, []byte(test.body))
+		if test.items == 0 {
+			if err == nil {
+				t.Errorf("%s: expected error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.name, err.Error())
+			continue
+		}
+		if len(items) != test.items {
+			t.Errorf("%s: expected %d items, got %d", test.name, test.items, len(items))
+		}
+	}
+}
+
+func TestBatchHandler(t *testing.T) {
+
+	var lock sync.Mutex
+	var published []string
+	eventBus := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var event KymaEvent
+		json.Unmarshal(body, &event)
+
+		lock.Lock()
+		published = append(published, event.EventType)
+		lock.Unlock()
+
+		if event.EventType == "rejected" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"event-id":"x"}`))
+	}))
+	defer eventBus.Close()
+
+	k := newTestProcesser(t, eventBus.URL, "events")
+
+	body := `{"events":[{"type":"a"},{"other":"b"},{"type":"rejected"},{"type":"c"}]}`
+	recorder := httptest.NewRecorder()
+	k.BatchHandler(recorder, httptest.NewRequest(http.MethodPost, "/events/batch", strings.NewReader(body)))
+
+	if recorder.Code != http.StatusMultiStatus {
+		t.Errorf("expected status %d, got %d", http.StatusMultiStatus, recorder.Code)
+	}
+
+	var report BatchReport
+	if err := json.Unmarshal(recorder.Body.Bytes(), &report); err != nil {
+		t.Fatalf("invalid report: %s", err.Error())
+	}
+	if report.Total != 4 || report.Succeeded != 2 || report.Failed != 2 {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	expected := []int{http.StatusOK, http.StatusBadRequest, http.StatusBadRequest, http.StatusOK}
+	for i, result := range report.Results {
+		if result.Index != i || result.Status != expected[i] {
+			t.Errorf("unexpected result %d: %+v", i, result.Result)
+		}
+	}
+	if len(published) != 3 {
+		t.Errorf("expected 3 published events, got %v", published)
+	}
+
+	recorder = httptest.NewRecorder()
+	k.BatchHandler(recorder, httptest.NewRequest(http.MethodPost, "/events/batch", strings.NewReader(`{"events":[{"type":"a"}]}`)))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+}
+
+func TestProcessBatch_Deadline(t *testing.T) {
+
+	release := make(chan struct{})
+	eventBus := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte(`{"event-id":"x"}`))
+	}))
+	defer eventBus.Close()
+	defer close(release)
+
+	k := newTestProcesser(t, eventBus.URL, "")
+	k.batchConcurrency = 1
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	report := k.processBatch(ctx, "", [][]byte{[]byte(`{"type":"a"}`), []byte(`{"type":"b"}`), []byte(`{"type":"c"}`)})
+
+	if report.Total != 3 || report.Failed != 3 {
+		t.Errorf("expected every event to fail after the deadline, got %+v", report)
+	}
+	for i, result := range report.Results {
+		if result.Index != i || result.Status != http.StatusServiceUnavailable {
+			t.Errorf("unexpected result %d: %+v", i, result.Result)
+		}
+	}
+	if !strings.Contains(report.Results[0].Message, "may still be forwarded") {
+		t.Errorf("expected the started event to be reported as possibly forwarded, got %s", report.Results[0].Message)
+	}
+}
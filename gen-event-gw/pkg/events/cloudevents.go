@@ -0,0 +1,99 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+)
+
+const (
+	//FormatLegacy - kyma event body as accepted by /v1/events
+	FormatLegacy = "legacy"
+	//FormatStructured - CloudEvents 1.0 structured content mode (application/cloudevents+json)
+	FormatStructured = "structured"
+	//FormatBinary - CloudEvents 1.0 binary content mode (ce-* headers)
+	FormatBinary = "binary"
+
+	cloudEventsSpecVersion = "1.0"
+	cloudEventsContentType = "application/cloudevents+json"
+	dataContentType        = "application/json"
+)
+
+//CloudEvent - CloudEvents 1.0 envelope used in structured mode
+type CloudEvent struct {
+	SpecVersion      string     `json:"specversion"`
+	Type             string     `json:"type"`
+	Source           string     `json:"source"`
+	ID               string     `json:"id"`
+	Time             string     `json:"time,omitempty"`
+	DataContentType  string     `json:"datacontenttype,omitempty"`
+	EventTypeVersion string     `json:"eventtypeversion,omitempty"`
+	Data             jsonString `json:"data"`
+}
+
+//ToCloudEvent - maps the kyma event onto the CloudEvents attributes
+func (k *KymaEvent) ToCloudEvent() *CloudEvent {
+	var source string
+	if k.SourceID != nil {
+		source = *k.SourceID
+	}
+
+	return &CloudEvent{
+		SpecVersion:      cloudEventsSpecVersion,
+		Type:             k.EventType,
+		Source:           source,
+		ID:               k.EventID,
+		Time:             k.EventTime,
+		DataContentType:  dataContentType,
+		EventTypeVersion: k.EventTypeVersion,
+		Data:             k.Data,
+	}
+}
+
+//newPublishRequest - builds the publishing request for the given output format
+func newPublishRequest(url string, format string, event *KymaEvent) (*http.Request, error) {
+	var body []byte
+	var contentType string
+	var err error
+
+	switch format {
+	case FormatStructured:
+		body, err = json.Marshal(event.ToCloudEvent())
+		contentType = cloudEventsContentType
+	case FormatBinary:
+		body = []byte(event.Data)
+		contentType = dataContentType
+	default:
+		body, err = json.Marshal(event)
+		contentType = dataContentType
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", contentType)
+
+	if format == FormatBinary {
+		ce := event.ToCloudEvent()
+		setHeader(req, "ce-specversion", ce.SpecVersion)
+		setHeader(req, "ce-type", ce.Type)
+		setHeader(req, "ce-source", ce.Source)
+		setHeader(req, "ce-id", ce.ID)
+		setHeader(req, "ce-time", ce.Time)
+		setHeader(req, "ce-eventtypeversion", ce.EventTypeVersion)
+	}
+
+	return req, nil
+}
+
+//setHeader - optional attributes that are unset are not sent as empty headers
+func setHeader(req *http.Request, name string, value string) {
+	if value != "" {
+		req.Header.Set(name, value)
+	}
+}
@@ -0,0 +1,101 @@
+package events
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestNewPublishRequest(t *testing.T) {
+	source := "shop"
+
+	tests := []struct {
+		name        string
+		format      string
+		event       *KymaEvent
+		contentType string
+		headers     map[string]string
+		body        string
+	}{
+		{
+			name:   "structured",
+			format: FormatStructured,
+			event: &KymaEvent{SourceID: &source, EventType: "order.created", EventTypeVersion: "v1",
+				EventID: "A-1", EventTime: "2019-10-01T10:00:00Z", Data: jsonString(`{"b":1,"a":2}`)},
+			contentType: cloudEventsContentType,
+			headers:     map[string]string{"ce-specversion": "", "ce-type": "", "ce-id": ""},
+			body: `{"specversion":"1.0","type":"order.created","source":"shop","id":"A-1","time":"2019-10-01T10:00:00Z",` +
+				`"datacontenttype":"application/json","eventtypeversion":"v1","data":{"b":1,"a":2}}`,
+		},
+		{
+			name:   "structured without time",
+			format: FormatStructured,
+			event: &KymaEvent{SourceID: &source, EventType: "order.created", EventTypeVersion: "v1",
+				EventID: "A-1", Data: jsonString(`{}`)},
+			contentType: cloudEventsContentType,
+			body: `{"specversion":"1.0","type":"order.created","source":"shop","id":"A-1",` +
+				`"datacontenttype":"application/json","eventtypeversion":"v1","data":{}}`,
+		},
+		{
+			name:   "binary",
+			format: FormatBinary,
+			event: &KymaEvent{SourceID: &source, EventType: "order.created", EventTypeVersion: "v1",
+				EventID: "A-1", EventTime: "2019-10-01T10:00:00Z", Data: jsonString(`{"b":1,"a":2}`)},
+			contentType: dataContentType,
+			headers: map[string]string{
+				"ce-specversion":      "1.0",
+				"ce-type":             "order.created",
+				"ce-source":           "shop",
+				"ce-id":               "A-1",
+				"ce-time":             "2019-10-01T10:00:00Z",
+				"ce-eventtypeversion": "v1",
+			},
+			body: `{"b":1,"a":2}`,
+		},
+		{
+			name:        "binary without time and version",
+			format:      FormatBinary,
+			event:       &KymaEvent{SourceID: &source, EventType: "order.created", EventID: "A-1", Data: jsonString(`{}`)},
+			contentType: dataContentType,
+			headers: map[string]string{
+				"ce-specversion":      "1.0",
+				"ce-type":             "order.created",
+				"ce-source":           "shop",
+				"ce-id":               "A-1",
+				"ce-time":             "",
+				"ce-eventtypeversion": "",
+			},
+			body: `{}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, err := newPublishRequest("http://event-bus/events", test.format, test.event)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err.Error())
+			}
+
+			if req.Method != http.MethodPost {
+				t.Errorf("expected method %s, got %s", http.MethodPost, req.Method)
+			}
+			if got := req.Header.Get("Content-Type"); got != test.contentType {
+				t.Errorf("expected content type %q, got %q", test.contentType, got)
+			}
+			for name, expected := range test.headers {
+				values, present := req.Header[http.CanonicalHeaderKey(name)]
+				if expected == "" && present {
+					t.Errorf("header %s should not be set, got %q", name, values)
+				}
+				if expected != "" && req.Header.Get(name) != expected {
+					t.Errorf("expected header %s %q, got %q", name, expected, req.Header.Get(name))
+				}
+			}
+
+			body, _ := ioutil.ReadAll(req.Body)
+			if string(body) != test.body {
+				t.Errorf("unexpected body\nexpected %s\ngot      %s", test.body, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/config"
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/outbox"
+)
+
+//NewOutbox - creates the outbox if an outbox directory is configured, nil otherwise
+func NewOutbox(outboxConfig config.OutboxConfig) (*outbox.Outbox, error) {
+	if outboxConfig.Dir == "" {
+		return nil, nil
+	}
+
+	return outbox.New(outbox.Config{
+		Dir:            outboxConfig.Dir,
+		Workers:        outboxConfig.Workers,
+		MaxAttempts:    outboxConfig.MaxAttempts,
+		InitialBackoff: outboxConfig.InitialBackoff,
+		MaxBackoff:     outboxConfig.MaxBackoff,
+	})
+}
+
+//storedEvent - event waiting in the outbox for the delivery to a target
+//Without a target the event is routed again, as it is the case for apps without targets
+type storedEvent struct {
+	KymaEvent
+	Target string `json:"target,omitempty"`
+}
+
+//StartDelivery - forwards the stored events with the processer of the app they were received for
+func StartDelivery(o *outbox.Outbox, processers map[string]*KymaEventProcesser) error {
+	return o.Start(func(payload []byte) error {
+		var stored storedEvent
+		if err := json.Unmarshal(payload, &stored); err != nil {
+			return outbox.Permanent(err)
+		}
+		event := &stored.KymaEvent
+		if event.SourceID == nil || processers[*event.SourceID] == nil {
+			return outbox.Permanent(fmt.Errorf("event %s belongs to an unknown app", event.EventID))
+		}
+		k := processers[*event.SourceID]
+
+		targets := k.routing.match(event)
+		if stored.Target != "" {
+			t, ok := k.routing.targets[stored.Target]
+			if !ok {
+				return outbox.Permanent(fmt.Errorf("event %s belongs to the unknown target %s", event.EventID, stored.Target))
+			}
+			targets = []target{t}
+		}
+
+		for _, result := range k.deliver(event, targets) {
+			if result.Delivered {
+				continue
+			}
+			err := fmt.Errorf("target %s: %s", result.Target, result.Error)
+			if result.Permanent {
+				return outbox.Permanent(err)
+			}
+			return err
+		}
+		return nil
+	})
+}
+
+//storeEvent - persists the event in the outbox for background delivery, one entry for every configured target
+//so failing targets are retried on their own
+func (k *KymaEventProcesser) storeEvent(event *KymaEvent, targets []target) error {
+	if !k.routing.explicit {
+		payload, err := json.Marshal(&storedEvent{KymaEvent: *event})
+		if err != nil {
+			return err
+		}
+		return k.outbox.Put(event.EventID, payload)
+	}
+
+	for _, t := range targets {
+		payload, err := json.Marshal(&storedEvent{KymaEvent: *event, Target: t.targetName()})
+		if err != nil {
+			return err
+		}
+		if err := k.outbox.Put(event.EventID+"."+t.targetName(), payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//isPermanent - client errors of the event bus will not go away by retrying
+func isPermanent(err error) bool {
+	var publishErr *PublishError
+	if !errors.As(err, &publishErr) {
+		return false
+	}
+
+	switch publishErr.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return false
+	}
+	return publishErr.StatusCode >= 400 && publishErr.StatusCode < 500
+}
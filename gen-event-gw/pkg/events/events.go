@@ -1,58 +1,452 @@
 package events
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/capture"
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/config"
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/convert"
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/eventtype"
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/metrics"
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/outbox"
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/schema"
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/transform"
 
 	log "github.com/sirupsen/logrus"
 )
 
+//drainInterval - interval of checking for in-flight events during shutdown
+const drainInterval = 50 * time.Millisecond
+
 //KymaEventProcesser - consumption and forwarding of events
 type KymaEventProcesser struct {
-	outbound *EventForwarder
+	appName     string
+	routing     *routing
+	resolver    *eventtype.Resolver
+	transformer *transform.Transformer
+	validator   *schema.Validator
+	quarantine  *quarantine
+	outbox      *outbox.Outbox
+	dedup       *deduplicator
+	archive     *capture.Archive
+	eventTypes  metrics.EventTypes
+
+	credentialHeaders []string
+	batchPath         string
+	batchConcurrency  int
+	inFlight          int64
 }
 
-//NewKymaEventProcesser -
-func NewKymaEventProcesser() *KymaEventProcesser {
-	return &KymaEventProcesser{
-		outbound: NewEventForwarder(),
+//NewKymaEventProcesser - processer of the events of one app, events are stored in the outbox if it is not nil
+//and the received requests are recorded in the archive if it is not nil
+func NewKymaEventProcesser(app *config.AppConfig, o *outbox.Outbox, archive *capture.Archive) (*KymaEventProcesser, error) {
+	resolver, err := NewEventTypeResolver(app)
+	if err != nil {
+		return nil, fmt.Errorf("app %s: %s", app.Name, err.Error())
+	}
+
+	transformer, err := NewTransformer(app)
+	if err != nil {
+		return nil, fmt.Errorf("app %s: %s", app.Name, err.Error())
+	}
+
+	validator, err := NewValidator(app)
+	if err != nil {
+		return nil, fmt.Errorf("app %s: %s", app.Name, err.Error())
 	}
+
+	var q *quarantine
+	if app.Schema.Action == config.SchemaActionQuarantine {
+		q, err = newQuarantine(app.Schema.QuarantineFile)
+		if err != nil {
+			return nil, fmt.Errorf("app %s: error creating quarantine directory: %s", app.Name, err.Error())
+		}
+	}
+
+	dedup, err := newDeduplicator(app.Dedup)
+	if err != nil {
+		return nil, fmt.Errorf("app %s: %s", app.Name, err.Error())
+	}
+
+	//event types of the metric labels
+	var routeTypes []string
+	for _, route := range app.Routes {
+		routeTypes = append(routeTypes, route.EventTypes...)
+	}
+	eventTypes := metrics.NewEventTypes(resolver.Types(), validator.EventTypes(), routeTypes)
+
+	routing, err := newRouting(app, eventTypes)
+	if err != nil {
+		return nil, fmt.Errorf("app %s: %s", app.Name, err.Error())
+	}
+
+	return &KymaEventProcesser{
+		appName:     app.Name,
+		routing:     routing,
+		resolver:    resolver,
+		transformer: transformer,
+		validator:   validator,
+		quarantine:  q,
+		outbox:      o,
+		dedup:       dedup,
+		archive:     archive,
+		eventTypes:  eventTypes,
+
+		credentialHeaders: []string{app.Auth.APIKeyHeader},
+		batchPath:         app.Batch.Path,
+		batchConcurrency:  app.Batch.Concurrency,
+	}, nil
+}
+
+//Result - outcome of processing a single event
+type Result struct {
+	Status     int                `json:"status"`
+	EventType  string             `json:"eventType,omitempty"`
+	EventID    string             `json:"eventId,omitempty"`
+	Message    string             `json:"message"`
+	Violations []schema.Violation `json:"violations,omitempty"`
+	Targets    []TargetResult     `json:"targets,omitempty"`
 }
 
 //EventsHandler - route to handle events
 func (k *KymaEventProcesser) EventsHandler(w http.ResponseWriter, r *http.Request) {
 
-	// w.Header().Set("Content-Type", "application/json")
+	//w.Header().Set("Content-Type", "application/json")
+
+	metrics.EventsReceived.Inc()
 
 	reqBody, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		log.Println(err)
+		metrics.EventsRejected.WithLabelValues(metrics.ReasonReadError).Inc()
 		w.WriteHeader(http.StatusBadRequest)
 		fmt.Fprintf(w, "Could not read data")
 		return
 	}
 
-	kymaEvent, err := InBoundProcesser(reqBody)
+	result := k.handleEvent(r, reqBody)
+	k.record(r, reqBody, false, result)
 
+	if len(result.Violations) > 0 || len(result.Targets) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(result.Status)
+		_ = json.NewEncoder(w).Encode(result)
+		return
+	}
+	w.WriteHeader(result.Status)
+	fmt.Fprintf(w, "%s", result.Message)
+}
+
+//handleEvent - converts the received data to JSON and processes it
+func (k *KymaEventProcesser) handleEvent(r *http.Request, reqBody []byte) *Result {
+	data, err := convert.ToJSON(r.Header.Get("Content-Type"), reqBody)
 	if err != nil {
 		log.Println(err)
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(w, err.Error())
-		return
+		metrics.EventsRejected.WithLabelValues(metrics.ReasonConversion).Inc()
+		return &Result{Status: http.StatusBadRequest, Message: fmt.Sprintf("Could not convert data - %s", err.Error())}
 	}
-	log.Printf("kymaEvent: %+v \n", kymaEvent)
 
-	resp, err := k.outbound.ForwardEvent(kymaEvent)
+	var deliveryID string
+	if k.dedup != nil {
+		deliveryID = k.dedup.deliveryID(r)
+	}
+
+	return k.processEvent(deliveryID, data)
+}
+
+//processEvent - builds the kyma event of the received data and stores or forwards it
+func (k *KymaEventProcesser) processEvent(deliveryID string, reqBody []byte) *Result {
+	var dedupKey string
+	if k.dedup != nil {
+		dedupKey = k.dedup.key(deliveryID, reqBody)
+		switch k.dedup.claim(dedupKey) {
+		case claimInFlight:
+			metrics.EventsRejected.WithLabelValues(metrics.ReasonInFlight).Inc()
+			id := eventID(k.appName, dedupKey)
+			log.Printf("Event is being processed - %s \n", id)
+			return &Result{
+				Status:  http.StatusConflict,
+				EventID: id,
+				Message: fmt.Sprintf("Event is being processed - %s \n", id),
+			}
+		case claimDelivered:
+			metrics.EventsDuplicate.Inc()
+			id := eventID(k.appName, dedupKey)
+			log.Printf("Event already received - %s \n", id)
+			return &Result{
+				Status:  http.StatusOK,
+				EventID: id,
+				Message: fmt.Sprintf("Event already received - %s \n", id),
+			}
+		}
+		defer k.dedup.release(dedupKey)
+	}
+
+	kymaEvent, err := k.InBoundProcesser(reqBody)
 
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		fmt.Fprintf(w, "An error occurred during event publishing - %+v \n", err.Error())
-		log.Printf("An error occurred during event publishing - %+v \n", err.Error())
-	} else {
-		w.WriteHeader(http.StatusOK)
-		fmt.Fprintf(w, "Event published - %+v \n", resp)
-		log.Printf("Event published - %+v \n", resp)
+		log.Println(err)
+		k.countRejection(err)
+		var validationErr *schema.ValidationError
+		if errors.As(err, &validationErr) {
+			return k.handleViolation(dedupKey, reqBody, validationErr)
+		}
+		k.forget(dedupKey)
+		return &Result{Status: http.StatusBadRequest, Message: err.Error()}
+	}
+	if k.dedup != nil {
+		kymaEvent.EventID = eventID(k.appName, dedupKey)
+	}
+	log.Printf("kymaEvent: %+v \n", kymaEvent)
+
+	targets := k.routing.match(kymaEvent)
+	if len(targets) == 0 {
+		metrics.EventsRejected.WithLabelValues(metrics.ReasonRouting).Inc()
+		k.forget(dedupKey)
+		log.Printf("No route matched event type %s \n", kymaEvent.EventType)
+		return &Result{
+			Status:    http.StatusUnprocessableEntity,
+			EventType: kymaEvent.EventType,
+			EventID:   kymaEvent.EventID,
+			Message:   fmt.Sprintf("No route matched event type %s \n", kymaEvent.EventType),
+		}
+	}
+
+	if k.outbox != nil {
+		err = k.storeEvent(kymaEvent, targets)
+		if err != nil {
+			metrics.EventsRejected.WithLabelValues(metrics.ReasonPersistence).Inc()
+			k.forget(dedupKey)
+			log.Printf("An error occurred during event persisting - %+v \n", err.Error())
+			return &Result{
+				Status:  http.StatusInternalServerError,
+				EventID: kymaEvent.EventID,
+				Message: fmt.Sprintf("An error occurred during event persisting - %+v \n", err.Error()),
+			}
+		}
+		log.Printf("Event accepted - %s \n", kymaEvent.EventID)
+		return &Result{
+			Status:    http.StatusAccepted,
+			EventType: kymaEvent.EventType,
+			EventID:   kymaEvent.EventID,
+			Message:   fmt.Sprintf("Event accepted - %s \n", kymaEvent.EventID),
+		}
 	}
 
+	if k.dedup != nil && k.routing.explicit {
+		targets = k.undelivered(dedupKey, targets)
+		if len(targets) == 0 {
+			log.Printf("Event already published to all targets - %s \n", kymaEvent.EventID)
+			return &Result{
+				Status:    http.StatusOK,
+				EventType: kymaEvent.EventType,
+				EventID:   kymaEvent.EventID,
+				Message:   fmt.Sprintf("Event already published to all targets - %s \n", kymaEvent.EventID),
+			}
+		}
+	}
+
+	result := k.publishResult(kymaEvent, k.deliver(kymaEvent, targets))
+	if result.Status != http.StatusOK {
+		k.forgetFailed(dedupKey, result.Targets)
+		k.forget(dedupKey)
+	}
+	return result
+}
+
+//undelivered - marks the targets as delivered and returns those that have not received the event before,
+//so a retry of the sender only reaches the targets that failed
+func (k *KymaEventProcesser) undelivered(dedupKey string, targets []target) []target {
+	var result []target
+	for _, t := range targets {
+		if k.dedup.store.Add(targetDedupKey(dedupKey, t.targetName())) {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+//forgetFailed - the targets the event could not be delivered to receive the retry of the sender
+func (k *KymaEventProcesser) forgetFailed(dedupKey string, results []TargetResult) {
+	for _, targetResult := range results {
+		if !targetResult.Delivered {
+			k.forget(targetDedupKey(dedupKey, targetResult.Target))
+		}
+	}
+}
+
+func targetDedupKey(dedupKey string, targetName string) string {
+	return dedupKey + "/" + targetName
+}
+
+//deliver - forwards the event to the targets, the forwards are tracked for draining
+func (k *KymaEventProcesser) deliver(event *KymaEvent, targets []target) []TargetResult {
+	atomic.AddInt64(&k.inFlight, 1)
+	defer atomic.AddInt64(&k.inFlight, -1)
+
+	return forwardAll(event, targets)
+}
+
+//publishResult - the event is published if all targets received it, the results of the targets are only
+//reported if targets are configured. A failed delivery to configured targets is a bad gateway, the event
+//itself is fine
+func (k *KymaEventProcesser) publishResult(kymaEvent *KymaEvent, results []TargetResult) *Result {
+	result := &Result{
+		Status:    http.StatusOK,
+		EventType: kymaEvent.EventType,
+		EventID:   kymaEvent.EventID,
+	}
+
+	failed := 0
+	for _, targetResult := range results {
+		if !targetResult.Delivered {
+			failed++
+			log.Printf("An error occurred during event publishing to %s - %+v \n", targetResult.Target, targetResult.Error)
+		}
+	}
+	if failed > 0 {
+		result.Status = http.StatusBadRequest
+		if k.routing.explicit {
+			result.Status = http.StatusBadGateway
+		}
+	}
+
+	if !k.routing.explicit {
+		if failed > 0 {
+			result.Message = fmt.Sprintf("An error occurred during event publishing - %+v \n", results[0].Error)
+			return result
+		}
+		log.Printf("Event published - %+v \n", results[0].Response)
+		result.Message = fmt.Sprintf("Event published - %+v \n", results[0].Response)
+		return result
+	}
+
+	result.Targets = results
+	if failed > 0 {
+		result.Message = fmt.Sprintf("An error occurred during event publishing to %d of %d targets \n", failed, len(results))
+		return result
+	}
+	log.Printf("Event published to %d targets \n", len(results))
+	result.Message = fmt.Sprintf("Event published to %d targets \n", len(results))
+	return result
+}
+
+//Drain - waits for the events that are currently forwarded, at most until the context is done
+func (k *KymaEventProcesser) Drain(ctx context.Context) error {
+	ticker := time.NewTicker(drainInterval)
+	defer ticker.Stop()
+
+	for atomic.LoadInt64(&k.inFlight) > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+//Close - releases the files of the processer
+func (k *KymaEventProcesser) Close() error {
+	if k.dedup == nil {
+		return nil
+	}
+	return k.dedup.store.Close()
+}
+
+//handleViolation - events violating their schema are rejected with 422 or quarantined
+func (k *KymaEventProcesser) handleViolation(dedupKey string, reqBody []byte, validationErr *schema.ValidationError) *Result {
+	if k.quarantine == nil {
+		k.forget(dedupKey)
+		return &Result{
+			Status:     http.StatusUnprocessableEntity,
+			EventType:  validationErr.EventType,
+			Message:    validationErr.Error(),
+			Violations: validationErr.Violations,
+		}
+	}
+
+	id, _ := generateEventID()
+	if k.dedup != nil {
+		id = eventID(k.appName, dedupKey)
+	}
+
+	if err := k.quarantine.put(k.appName, id, validationErr, reqBody); err != nil {
+		metrics.EventsRejected.WithLabelValues(metrics.ReasonPersistence).Inc()
+		k.forget(dedupKey)
+		log.Printf("An error occurred during event quarantining - %+v \n", err.Error())
+		return &Result{
+			Status:    http.StatusInternalServerError,
+			EventType: validationErr.EventType,
+			EventID:   id,
+			Message:   fmt.Sprintf("An error occurred during event quarantining - %+v \n", err.Error()),
+		}
+	}
+
+	log.Printf("Event quarantined - %s \n", id)
+	return &Result{
+		Status:     http.StatusAccepted,
+		EventType:  validationErr.EventType,
+		EventID:    id,
+		Message:    fmt.Sprintf("Event quarantined - %s \n", id),
+		Violations: validationErr.Violations,
+	}
+}
+
+//record - writes the request and the outcome to the capture archive, credentials are redacted
+func (k *KymaEventProcesser) record(r *http.Request, reqBody []byte, batch bool, result *Result) {
+	if k.archive == nil {
+		return
+	}
+
+	record := &capture.Record{
+		Time:      time.Now(),
+		App:       k.appName,
+		Batch:     batch,
+		Headers:   capture.Redact(r.Header, k.credentialHeaders...),
+		EventType: result.EventType,
+		EventID:   result.EventID,
+		Status:    result.Status,
+		Outcome:   strings.TrimSpace(result.Message),
+	}
+	record.SetBody(reqBody)
+
+	if err := k.archive.Write(record); err != nil {
+		log.Printf("Request could not be captured - %s", err.Error())
+	}
+}
+
+//forget - the sender has to be able to retry events that were not accepted
+func (k *KymaEventProcesser) forget(dedupKey string) {
+	if k.dedup != nil {
+		k.dedup.store.Remove(dedupKey)
+	}
+}
+
+//countRejection - records the rejection reason of a processing error
+func (k *KymaEventProcesser) countRejection(err error) {
+	var processingErr *ProcessingError
+	if !errors.As(err, &processingErr) {
+		return
+	}
+
+	metrics.EventsRejected.WithLabelValues(processingErr.Reason).Inc()
+	if processingErr.Reason == metrics.ReasonTypeResolution {
+		metrics.TypeResolutionFailed.Inc()
+	}
+
+	var validationErr *schema.ValidationError
+	if errors.As(err, &validationErr) {
+		for _, violation := range validationErr.Violations {
+			metrics.SchemaViolations.WithLabelValues(k.eventTypes.Label(validationErr.EventType), violation.Rule).Inc()
+		}
+	}
 }
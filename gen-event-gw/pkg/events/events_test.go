@@ -0,0 +1,124 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/config"
+)
+
+func TestEventsHandler_Schema(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "quarantine")
+	defer os.RemoveAll(dir)
+
+	published := 0
+	eventBus := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		published++
+		w.Write([]byte(`{"event-id":"x"}`))
+	}))
+	defer eventBus.Close()
+
+	app := &config.AppConfig{
+		Name:            "shop",
+		EventTypeQuery:  "type",
+		EventPublishURL: eventBus.URL,
+		EventFormat:     "legacy",
+		Schema:          config.SchemaConfig{Config: "testdata/schema.json", Action: config.SchemaActionReject},
+	}
+	k, err := NewKymaEventProcesser(app, nil, nil)
+	if err != nil {
+		t.Fatalf("processer creation failed: %s", err.Error())
+	}
+
+	recorder := httptest.NewRecorder()
+	k.EventsHandler(recorder, httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(`{"type":"order.created","id":"A-1"}`)))
+	if recorder.Code != http.StatusOK || published != 1 {
+		t.Errorf("valid event should be published, got %d", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	k.EventsHandler(recorder, httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(`{"type":"order.created","id":1}`)))
+	if recorder.Code != http.StatusUnprocessableEntity || published != 1 {
+		t.Errorf("invalid event should be rejected with %d, got %d", http.StatusUnprocessableEntity, recorder.Code)
+	}
+
+	var result Result
+	if err := json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
+		t.Fatalf("invalid response: %s", err.Error())
+	}
+	if len(result.Violations) != 1 || result.Violations[0].Path != "id" || result.Violations[0].Rule != "invalid_type" {
+		t.Errorf("unexpected violations %+v", result.Violations)
+	}
+
+	quarantineFile := filepath.Join(dir, "quarantine.ndjson")
+	app.Schema = config.SchemaConfig{Config: "testdata/schema.json", Action: config.SchemaActionQuarantine, QuarantineFile: quarantineFile}
+	k, err = NewKymaEventProcesser(app, nil, nil)
+	if err != nil {
+		t.Fatalf("processer creation failed: %s", err.Error())
+	}
+
+	recorder = httptest.NewRecorder()
+	k.EventsHandler(recorder, httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(`{"type":"order.created"}`)))
+	if recorder.Code != http.StatusAccepted || published != 1 {
+		t.Errorf("invalid event should be quarantined, got %d", recorder.Code)
+	}
+
+	var record quarantineRecord
+	data, _ := ioutil.ReadFile(quarantineFile)
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatalf("invalid quarantine record: %s", err.Error())
+	}
+	if record.App != "shop" || record.EventType != "order.created" || string(record.Data) != `{"type":"order.created"}` || record.Violations[0].Path != "id" {
+		t.Errorf("unexpected quarantine record %+v", record)
+	}
+}
+
+func TestKymaEventProcesser_Drain(t *testing.T) {
+
+	release := make(chan struct{})
+	eventBus := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte(`{"event-id":"x"}`))
+	}))
+	defer eventBus.Close()
+
+	k := newTestProcesser(t, eventBus.URL, "")
+
+	forwarded := make(chan *Result)
+	go func() {
+		forwarded <- k.processEvent("", []byte(`{"type":"order.created"}`))
+	}()
+
+	//waits until the event is forwarded
+	for i := 0; i < 100 && k.Drain(expiredContext()) == nil; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := k.Drain(expiredContext()); err == nil {
+		t.Error("drain should time out while an event is forwarded")
+	}
+
+	close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := k.Drain(ctx); err != nil {
+		t.Errorf("drain should succeed once the event is forwarded: %s", err.Error())
+	}
+	if result := <-forwarded; result.Status != http.StatusOK {
+		t.Errorf("unexpected result %+v", result)
+	}
+}
+
+func expiredContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
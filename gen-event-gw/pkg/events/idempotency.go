@@ -0,0 +1,119 @@
+package events
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/config"
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/dedup"
+
+	"github.com/gofrs/uuid"
+	"github.com/tidwall/gjson"
+)
+
+//eventIDNamespace - namespace of the event ids derived from delivery ids
+var eventIDNamespace = uuid.NewV5(uuid.NamespaceURL, "https://github.com/kyma-incubator/connector-tools/gen-event-gw")
+
+//claim - outcome of claiming the delivery id of a received event
+type claim int
+
+const (
+	//claimed - the delivery id is new, the event is processed
+	claimed claim = iota
+	//claimInFlight - a copy of the event is being processed, the sender should retry later
+	claimInFlight
+	//claimDelivered - the event has been processed before
+	claimDelivered
+)
+
+//deduplicator - detects redelivered events by their delivery id
+//A delivery id stays in flight until its event is processed, so a concurrent copy is not acknowledged
+//before it is known whether the first attempt succeeds
+type deduplicator struct {
+	header string
+	path   string
+	store  *dedup.Store
+
+	lock     sync.Mutex
+	inFlight map[string]bool
+}
+
+//newDeduplicator - nil if duplicate detection is not enabled for the app
+func newDeduplicator(dedupConfig config.DedupConfig) (*deduplicator, error) {
+	if !dedupConfig.Enabled {
+		return nil, nil
+	}
+
+	store, err := dedup.New(dedup.Config{
+		TTL:        dedupConfig.TTL,
+		MaxEntries: dedupConfig.MaxEntries,
+		File:       dedupConfig.File,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &deduplicator{
+		header:   dedupConfig.Header,
+		path:     dedupConfig.Path,
+		store:    store,
+		inFlight: make(map[string]bool),
+	}, nil
+}
+
+//claim - records the key and marks it in flight if it has not been seen before
+func (d *deduplicator) claim(key string) claim {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.inFlight[key] {
+		return claimInFlight
+	}
+	if !d.store.Add(key) {
+		return claimDelivered
+	}
+	d.inFlight[key] = true
+	return claimed
+}
+
+//release - the event of the key is processed, a failed event must be forgotten before it is released
+func (d *deduplicator) release(key string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	delete(d.inFlight, key)
+}
+
+//deliveryID - delivery id sent in the configured header, empty if none is sent
+func (d *deduplicator) deliveryID(r *http.Request) string {
+	if d.header == "" {
+		return ""
+	}
+	return r.Header.Get(d.header)
+}
+
+//key - the delivery id of the header or the gjson path, the hash of the body if neither is present
+func (d *deduplicator) key(deliveryID string, body []byte) string {
+	if deliveryID != "" {
+		return deliveryID
+	}
+
+	if d.path != "" {
+		if value := gjson.GetBytes(body, d.path); value.Exists() && value.String() != "" {
+			return value.String()
+		}
+	}
+
+	hash := sha256.Sum256(body)
+	return "sha256:" + hex.EncodeToString(hash[:])
+}
+
+//eventID - delivery ids that are uuids are used as event id, other keys are mapped to a name based uuid
+func eventID(appName string, key string) string {
+	if id, err := uuid.FromString(key); err == nil {
+		return id.String()
+	}
+	return uuid.NewV5(eventIDNamespace, appName+"/"+key).String()
+}
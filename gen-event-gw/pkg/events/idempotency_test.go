@@ -0,0 +1,149 @@
+package events
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/config"
+)
+
+func TestDeduplicator_Key(t *testing.T) {
+
+	d, err := newDeduplicator(config.DedupConfig{Enabled: true, Header: "X-Delivery-Id", Path: "meta.id", TTL: time.Minute, MaxEntries: 10})
+	if err != nil {
+		t.Fatalf("deduplicator creation failed: %s", err.Error())
+	}
+
+	body := []byte(`{"meta":{"id":"order-1"}}`)
+
+	withHeader := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(string(body)))
+	withHeader.Header.Set("X-Delivery-Id", "delivery-1")
+	if key := d.key(d.deliveryID(withHeader), body); key != "delivery-1" {
+		t.Errorf("expected key of the header, got %s", key)
+	}
+
+	withoutHeader := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(string(body)))
+	if key := d.key(d.deliveryID(withoutHeader), body); key != "order-1" {
+		t.Errorf("expected key of the path, got %s", key)
+	}
+
+	other := []byte(`{"other":true}`)
+	if key := d.key("", other); key != d.key("", other) || !strings.HasPrefix(key, "sha256:") {
+		t.Errorf("expected stable hash of the body, got %s", key)
+	}
+}
+
+func TestEventID(t *testing.T) {
+
+	uuidKey := "6F1F8A0C-52E5-4C7B-9C4E-0F7C9B1D2A3E"
+	if id := eventID("shop", uuidKey); id != strings.ToLower(uuidKey) {
+		t.Errorf("uuid key should be used as event id, got %s", id)
+	}
+
+	id := eventID("shop", "order-1")
+	if id != eventID("shop", "order-1") {
+		t.Error("event id of the same key should be stable")
+	}
+	if id == eventID("crm", "order-1") {
+		t.Error("event ids of different apps should differ")
+	}
+}
+
+func TestDeduplicator_Claim(t *testing.T) {
+
+	d, err := newDeduplicator(config.DedupConfig{Enabled: true, TTL: time.Minute, MaxEntries: 10})
+	if err != nil {
+		t.Fatalf("deduplicator creation failed: %s", err.Error())
+	}
+
+	if c := d.claim("delivery-1"); c != claimed {
+		t.Errorf("expected a new key to be claimed, got %d", c)
+	}
+	if c := d.claim("delivery-1"); c != claimInFlight {
+		t.Errorf("expected the key to be in flight until it is released, got %d", c)
+	}
+
+	d.release("delivery-1")
+	if c := d.claim("delivery-1"); c != claimDelivered {
+		t.Errorf("expected a released key to be delivered, got %d", c)
+	}
+
+	d.claim("delivery-2")
+	d.store.Remove("delivery-2")
+	d.release("delivery-2")
+	if c := d.claim("delivery-2"); c != claimed {
+		t.Errorf("expected a forgotten key to be claimed again, got %d", c)
+	}
+}
+
+func TestProcessEvent_ConcurrentCopy(t *testing.T) {
+
+	arrived := make(chan struct{})
+	proceed := make(chan struct{})
+	var lock sync.Mutex
+	calls := 0
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		calls++
+		first := calls == 1
+		lock.Unlock()
+		if first {
+			close(arrived)
+			<-proceed
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"event-id":"x"}`))
+	}))
+	defer target.Close()
+
+	app := &config.AppConfig{
+		Name:           "shop",
+		EventTypeQuery: "type",
+		EventFormat:    "legacy",
+		Dedup:          config.DedupConfig{Enabled: true, Header: "X-Delivery-Id", TTL: time.Hour, MaxEntries: 100},
+		Targets:        []config.TargetConfig{{Name: "kyma", URL: target.URL, Format: "legacy"}},
+		Batch:          config.BatchConfig{Concurrency: 1},
+	}
+	k, err := NewKymaEventProcesser(app, nil, nil)
+	if err != nil {
+		t.Fatalf("processer creation failed: %s", err.Error())
+	}
+
+	send := func() int {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(`{"type":"order.created"}`))
+		req.Header.Set("X-Delivery-Id", "delivery-1")
+		k.EventsHandler(recorder, req)
+		return recorder.Code
+	}
+
+	first := make(chan int)
+	go func() { first <- send() }()
+	<-arrived
+
+	if status := send(); status != http.StatusConflict {
+		t.Errorf("expected a copy of an event in flight to conflict, got %d", status)
+	}
+
+	close(proceed)
+	if status := <-first; status != http.StatusBadGateway {
+		t.Errorf("expected the first attempt to fail, got %d", status)
+	}
+	if status := send(); status != http.StatusOK {
+		t.Errorf("expected the retry to be published, got %d", status)
+	}
+	if status := send(); status != http.StatusOK {
+		t.Errorf("expected the duplicate to be acknowledged, got %d", status)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if calls != 2 {
+		t.Errorf("expected the failed attempt and the retry to be forwarded, got %d forwards", calls)
+	}
+}
@@ -5,9 +5,13 @@ import (
 	"time"
 
 	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/config"
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/eventtype"
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/metrics"
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/schema"
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/transform"
 
 	"github.com/gofrs/uuid"
-	"github.com/tidwall/gjson"
+	log "github.com/sirupsen/logrus"
 )
 
 //unmarshall will alter the property order - using custom type to prevent
@@ -18,6 +22,12 @@ func (j jsonString) MarshalJSON() ([]byte, error) {
 	return []byte(j), nil
 }
 
+//UnmarshalJSON keeps the raw data, e.g. when reading events from the outbox
+func (j *jsonString) UnmarshalJSON(data []byte) error {
+	*j = jsonString(data)
+	return nil
+}
+
 //KymaEvent - data structure kyma supports
 type KymaEvent struct {
 	SourceID         *string    `json:"source-id"`
@@ -28,37 +38,80 @@ type KymaEvent struct {
 	Data             jsonString `json:"data"`
 }
 
+//ProcessingError - the received event was rejected, the reason is used as metric label
+type ProcessingError struct {
+	Reason string
+	Err    error
+}
+
+func (p *ProcessingError) Error() string {
+	return p.Err.Error()
+}
+
+func (p *ProcessingError) Unwrap() error {
+	return p.Err
+}
+
 //InBoundProcesser - consumes event and transforms to kyma event
-func InBoundProcesser(reqBody []byte) (*KymaEvent, error) {
+func (k *KymaEventProcesser) InBoundProcesser(reqBody []byte) (*KymaEvent, error) {
 
-	//Fieldglass implemenation
-	eventType := getEventType(string(reqBody))
-	println("eventType:", eventType)
+	eventType, eventTypeVersion, err := k.resolver.Resolve(string(reqBody))
+	if err != nil {
+		return nil, &ProcessingError{
+			Reason: metrics.ReasonTypeResolution,
+			Err:    fmt.Errorf("Could not determine an event type: %s", err.Error()),
+		}
+	}
+	log.Printf("eventType: %s, eventTypeVersion: %s", eventType, eventTypeVersion)
 
-	if eventType == "" {
-		return nil, fmt.Errorf("Could not determine an event type using  the query %s", *config.GlobalConfig.EventTypeQuery)
+	err = k.validator.Validate(eventType, reqBody)
+	if err != nil {
+		return nil, &ProcessingError{Reason: metrics.ReasonSchema, Err: err}
+	}
+
+	data, err := k.transformer.Apply(eventType, reqBody)
+	if err != nil {
+		return nil, &ProcessingError{Reason: metrics.ReasonTransformation, Err: err}
 	}
 
 	id, _ := generateEventID()
 
 	//build kyma event
 	var event = KymaEvent{
-		SourceID:         config.GlobalConfig.AppName,
-		EventTypeVersion: "v1",
+		SourceID:         &k.appName,
+		EventTypeVersion: eventTypeVersion,
 		EventTime:        time.Now().Format(time.RFC3339),
 		EventID:          id,
 		EventType:        eventType,
-		Data:             jsonString(reqBody),
+		Data:             jsonString(data),
 	}
 
 	return &event, nil
 }
 
-//GetFGEventType - determines the event type by checking the value of config.GlobalConfig.EventTypeQuery
+//NewEventTypeResolver - rule based resolver if a rule file is configured, query based otherwise
 //FieldGlass - "*.@xmlns"
-func getEventType(json string) string {
-	value := gjson.Get(json, *config.GlobalConfig.EventTypeQuery)
-	return value.String()
+func NewEventTypeResolver(app *config.AppConfig) (*eventtype.Resolver, error) {
+	if app.EventTypeRules != "" {
+		return eventtype.New(app.EventTypeRules)
+	}
+	return eventtype.NewFromQuery(app.EventTypeQuery), nil
+}
+
+//NewValidator - validator of the configured schema config, nil if not configured
+func NewValidator(app *config.AppConfig) (*schema.Validator, error) {
+	if app.Schema.Config == "" {
+		return nil, nil
+	}
+	return schema.New(app.Schema.Config)
+}
+
+//NewTransformer - transformer of the configured transformation config, nil if not configured
+func NewTransformer(app *config.AppConfig) (*transform.Transformer, error) {
+	if app.TransformConfig == "" {
+		return nil, nil
+	}
+	return transform.New(app.TransformConfig)
 }
 
 func generateEventID() (string, error) {
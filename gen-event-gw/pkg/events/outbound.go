@@ -1,58 +1,175 @@
 package events
 
 import (
-	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"time"
 
 	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/config"
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/metrics"
 
 	log "github.com/sirupsen/logrus"
 )
 
+//defaultTarget - name of the target built of the event publish url of an app without targets
+const defaultTarget = "default"
+
+//PublishError - the event bus answered with an unexpected status code
+type PublishError struct {
+	StatusCode int
+	Status     string
+}
+
+func (p *PublishError) Error() string {
+	return fmt.Sprintf("unexpected response when publishing event %d (%s)", p.StatusCode, p.Status)
+}
+
 //EventForwarder -  todo
 type EventForwarder struct {
-	eventPublishURL *string
+	name            string
+	eventPublishURL string
+	eventFormat     string
 	client          *http.Client
+	headers         map[string]string
+	auth            config.TargetAuth
+	//plainResponse - responses of plain HTTP endpoints do not have to be JSON
+	plainResponse bool
+	//slots - bounds the concurrent forwards, nil if unlimited
+	slots chan struct{}
+	//eventTypes - bound the event type labels of the metrics
+	eventTypes metrics.EventTypes
 }
 
-//NewEventForwarder - todo
-func NewEventForwarder() *EventForwarder {
-	client := &http.Client{
-		Transport: &http.Transport{
-			DisableCompression:  false,
-			MaxIdleConns:        100,
-			MaxIdleConnsPerHost: 0,
-			MaxConnsPerHost:     0,
-			IdleConnTimeout:     30 * time.Second,
-		},
+//NewEventForwarder - forwards events to the publish url in the given event format, each request ends after the timeout
+//At most maxConcurrent events are forwarded at the same time, unlimited if it is 0
+func NewEventForwarder(eventPublishURL string, eventFormat string, maxConcurrent int, timeout time.Duration) *EventForwarder {
+	return &EventForwarder{
+		name:            defaultTarget,
+		eventPublishURL: eventPublishURL,
+		eventFormat:     eventFormat,
+		client:          &http.Client{Transport: newTransport(nil), Timeout: timeout},
+		slots:           newSlots(maxConcurrent),
+	}
+}
+
+//newHTTPTarget - forwarder of a kyma or http target with its own client settings and credentials
+func newHTTPTarget(targetConfig *config.TargetConfig, eventTypes metrics.EventTypes) (*EventForwarder, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: targetConfig.TLS.InsecureSkipVerify}
+	if targetConfig.TLS.CAFile != "" {
+		caData, err := ioutil.ReadFile(targetConfig.TLS.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA of target %s: %s", targetConfig.Name, err.Error())
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("CA of target %s does not contain any PEM encoded certificate", targetConfig.Name)
+		}
 	}
 
 	return &EventForwarder{
-		eventPublishURL: config.GlobalConfig.EventPublishURL,
-		client:          client,
+		name:            targetConfig.Name,
+		eventPublishURL: targetConfig.URL,
+		eventFormat:     targetConfig.Format,
+		client: &http.Client{
+			Transport: newTransport(tlsConfig),
+			Timeout:   targetConfig.Timeout,
+		},
+		headers:       targetConfig.Headers,
+		auth:          targetConfig.Auth,
+		plainResponse: targetConfig.Type == config.TargetHTTP,
+		slots:         newSlots(targetConfig.MaxConcurrentForwards),
+		eventTypes:    eventTypes,
+	}, nil
+}
+
+func newTransport(tlsConfig *tls.Config) *http.Transport {
+	return &http.Transport{
+		TLSClientConfig:     tlsConfig,
+		DisableCompression:  false,
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 0,
+		MaxConnsPerHost:     0,
+		IdleConnTimeout:     30 * time.Second,
+	}
+}
+
+func newSlots(maxConcurrent int) chan struct{} {
+	if maxConcurrent > 0 {
+		return make(chan struct{}, maxConcurrent)
 	}
+	return nil
+}
+
+func (e *EventForwarder) targetName() string {
+	return e.name
 }
 
 //ForwardEvent - submit events to the kyma event bus
 func (e *EventForwarder) ForwardEvent(event *KymaEvent) (map[string]interface{}, error) {
+	if e.slots != nil {
+		waiting := time.Now()
+		if err := e.acquireSlot(); err != nil {
+			metrics.ObserveForward(e.name, e.eventTypes.Label(event.EventType), waiting, err)
+			return nil, err
+		}
+		defer func() { <-e.slots }()
+	}
 
-	eventBytes, err := json.Marshal(event)
-	if err != nil {
-		log.Println(err)
-		return nil, err
+	start := time.Now()
+	metrics.InFlightForwards.Inc()
+
+	respMap, err := e.forward(event)
+
+	metrics.InFlightForwards.Dec()
+	metrics.ObserveForward(e.name, e.eventTypes.Label(event.EventType), start, err)
+
+	return respMap, err
+}
+
+//acquireSlot - waits for a free slot at most for the timeout of the client, so waiting forwards do not pile up
+//behind a hanging event bus
+func (e *EventForwarder) acquireSlot() error {
+	metrics.WaitingForwards.Inc()
+	defer metrics.WaitingForwards.Dec()
+
+	if e.client.Timeout <= 0 {
+		e.slots <- struct{}{}
+		return nil
+	}
+
+	timer := time.NewTimer(e.client.Timeout)
+	defer timer.Stop()
+
+	select {
+	case e.slots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("no free forwarding slot of %s within %s", e.name, e.client.Timeout)
 	}
+}
 
-	log.Printf("Will submit: %s", string(eventBytes))
+func (e *EventForwarder) forward(event *KymaEvent) (map[string]interface{}, error) {
 
-	req, err := http.NewRequest(http.MethodPost, *e.eventPublishURL, bytes.NewReader(eventBytes))
+	req, err := newPublishRequest(e.eventPublishURL, e.eventFormat, event)
 	if err != nil {
+		log.Println(err)
 		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	for name, value := range e.headers {
+		req.Header.Set(name, value)
+	}
+	if e.auth.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+e.auth.BearerToken)
+	} else if e.auth.Username != "" {
+		req.SetBasicAuth(e.auth.Username, e.auth.Password)
+	}
+
+	log.Printf("Will submit %s event to %s: %s", e.eventFormat, e.name, event.EventID)
 
 	resp, err := e.client.Do(req)
 	if err != nil {
@@ -60,17 +177,24 @@ func (e *EventForwarder) ForwardEvent(event *KymaEvent) (map[string]interface{},
 	}
 	defer resp.Body.Close()
 
-	dec := json.NewDecoder(resp.Body)
-	var respMap map[string]interface{}
-	err = dec.Decode(&respMap)
+	respBody, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		errMsg := fmt.Sprintf("unexpected response when publishing event %d (%s)", resp.StatusCode, resp.Status)
-		log.Println(errMsg)
-		return respMap, fmt.Errorf(errMsg)
+	//cloudevents endpoints usually answer with an empty 2xx response
+	var respMap map[string]interface{}
+	if len(respBody) > 0 {
+		err = json.Unmarshal(respBody, &respMap)
+		if err != nil && !e.plainResponse {
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		publishErr := &PublishError{StatusCode: resp.StatusCode, Status: resp.Status}
+		log.Println(publishErr.Error())
+		return respMap, publishErr
 	}
 
 	return respMap, nil
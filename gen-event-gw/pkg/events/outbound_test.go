@@ -0,0 +1,75 @@
+package events
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEventForwarder_MaxConcurrent(t *testing.T) {
+
+	var current, max int64
+	eventBus := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&current, 1)
+		for {
+			m := atomic.LoadInt64(&max)
+			if n <= m || atomic.CompareAndSwapInt64(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt64(&current, -1)
+		w.Write([]byte(`{"event-id":"x"}`))
+	}))
+	defer eventBus.Close()
+
+	forwarder := NewEventForwarder(eventBus.URL, "legacy", 2, time.Second)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := forwarder.ForwardEvent(&KymaEvent{EventType: "order.created", Data: jsonString(`{}`)}); err != nil {
+				t.Errorf("unexpected error: %s", err.Error())
+			}
+		}()
+	}
+	wg.Wait()
+
+	if max != 2 {
+		t.Errorf("expected at most 2 concurrent forwards, got %d", max)
+	}
+}
+
+func TestEventForwarder_Timeout(t *testing.T) {
+
+	release := make(chan struct{})
+	eventBus := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte(`{"event-id":"x"}`))
+	}))
+	defer eventBus.Close()
+	defer close(release)
+
+	forwarder := NewEventForwarder(eventBus.URL, "legacy", 1, 50*time.Millisecond)
+
+	if _, err := forwarder.ForwardEvent(&KymaEvent{EventType: "order.created", Data: jsonString(`{}`)}); err == nil {
+		t.Error("forward to a hanging event bus should time out")
+	}
+
+	//all slots are taken by forwards that do not finish
+	forwarder.slots <- struct{}{}
+	start := time.Now()
+	_, err := forwarder.ForwardEvent(&KymaEvent{EventType: "order.created", Data: jsonString(`{}`)})
+	if err == nil || !strings.Contains(err.Error(), "no free forwarding slot") {
+		t.Errorf("waiting for a slot should time out, got %v", err)
+	}
+	if waited := time.Since(start); waited > time.Second {
+		t.Errorf("waiting forward should give up after the timeout, waited %s", waited)
+	}
+}
@@ -0,0 +1,63 @@
+package events
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/schema"
+)
+
+//quarantineRecord - an event violating its schema, stored for inspection
+type quarantineRecord struct {
+	App        string             `json:"app"`
+	EventType  string             `json:"event-type"`
+	EventID    string             `json:"event-id"`
+	Received   string             `json:"received"`
+	Violations []schema.Violation `json:"violations"`
+	Data       jsonString         `json:"data"`
+}
+
+//quarantine - appends events violating their schema to a NDJSON file
+type quarantine struct {
+	file string
+	lock sync.Mutex
+}
+
+func newQuarantine(file string) (*quarantine, error) {
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return nil, err
+	}
+	return &quarantine{file: file}, nil
+}
+
+func (q *quarantine) put(appName string, eventID string, validationErr *schema.ValidationError, data []byte) error {
+	line, err := json.Marshal(&quarantineRecord{
+		App:        appName,
+		EventType:  validationErr.EventType,
+		EventID:    eventID,
+		Received:   time.Now().Format(time.RFC3339),
+		Violations: validationErr.Violations,
+		Data:       jsonString(data),
+	})
+	if err != nil {
+		return err
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	file, err := os.OpenFile(q.file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	//noinspection GoUnhandledErrorResult
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return file.Sync()
+}
@@ -0,0 +1,81 @@
+package events
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/capture"
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/convert"
+)
+
+//ReplayResult - kyma event built of a captured request with the current config, and the results of forwarding it
+type ReplayResult struct {
+	Time    time.Time      `json:"time"`
+	App     string         `json:"app"`
+	Index   *int           `json:"index,omitempty"`
+	Event   *KymaEvent     `json:"event,omitempty"`
+	Targets []string       `json:"targets,omitempty"`
+	Results []TargetResult `json:"results,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+//Replay - runs a captured request through the current pipeline, the events are only forwarded if forward is set
+//Duplicate detection and the outbox are bypassed, every replayed event gets a new event id
+func (k *KymaEventProcesser) Replay(record *capture.Record, forward bool) []*ReplayResult {
+	body, err := record.RawBody()
+	if err != nil {
+		return []*ReplayResult{{Time: record.Time, App: record.App, Error: err.Error()}}
+	}
+	contentType := record.Headers.Get("Content-Type")
+
+	if !record.Batch {
+		data, err := convert.ToJSON(contentType, body)
+		if err != nil {
+			return []*ReplayResult{{Time: record.Time, App: record.App, Error: err.Error()}}
+		}
+		return []*ReplayResult{k.replayEvent(record, nil, data, forward)}
+	}
+
+	items, err := k.splitBatch(contentType, body)
+	if err != nil {
+		return []*ReplayResult{{Time: record.Time, App: record.App, Error: err.Error()}}
+	}
+
+	results := make([]*ReplayResult, 0, len(items))
+	for i, item := range items {
+		index := i
+		results = append(results, k.replayEvent(record, &index, item, forward))
+	}
+	return results
+}
+
+func (k *KymaEventProcesser) replayEvent(record *capture.Record, index *int, data []byte, forward bool) *ReplayResult {
+	result := &ReplayResult{Time: record.Time, App: record.App, Index: index}
+
+	event, err := k.InBoundProcesser(data)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Event = event
+
+	targets := k.routing.match(event)
+	if len(targets) == 0 {
+		result.Error = fmt.Sprintf("no route matched event type %s", event.EventType)
+		return result
+	}
+	for _, t := range targets {
+		result.Targets = append(result.Targets, t.targetName())
+	}
+
+	if forward {
+		result.Results = k.deliver(event, targets)
+		for _, targetResult := range result.Results {
+			if !targetResult.Delivered {
+				result.Error = fmt.Sprintf("target %s: %s", targetResult.Target, targetResult.Error)
+				break
+			}
+		}
+	}
+	return result
+}
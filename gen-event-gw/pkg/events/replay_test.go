@@ -0,0 +1,38 @@
+package events
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/capture"
+)
+
+func TestReplay(t *testing.T) {
+
+	k := newTestProcesser(t, "http://localhost:1", "events")
+
+	record := &capture.Record{App: "shop", Headers: http.Header{"Content-Type": []string{"application/xml"}}}
+	record.SetBody([]byte(`<type>order.created</type>`))
+
+	results := k.Replay(record, false)
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+	if results[0].Error != "" {
+		t.Fatalf("unexpected error: %s", results[0].Error)
+	}
+	if results[0].Event.EventType != "order.created" || len(results[0].Targets) != 1 || results[0].Results != nil {
+		t.Errorf("expected dry-run event of type order.created, got %+v", results[0])
+	}
+
+	record = &capture.Record{App: "shop", Batch: true, Headers: http.Header{}}
+	record.SetBody([]byte(`{"events":[{"type":"a"},{"other":"b"}]}`))
+
+	results = k.Replay(record, false)
+	if len(results) != 2 || *results[1].Index != 1 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if results[0].Event.EventType != "a" || results[1].Error == "" {
+		t.Errorf("expected event of type a and an error, got %+v, %+v", results[0], results[1])
+	}
+}
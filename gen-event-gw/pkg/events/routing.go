@@ -0,0 +1,206 @@
+package events
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/config"
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/eventtype"
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/metrics"
+)
+
+//target - destination of routed events
+type target interface {
+	targetName() string
+	ForwardEvent(event *KymaEvent) (map[string]interface{}, error)
+}
+
+//TargetResult - outcome of delivering an event to a single target
+type TargetResult struct {
+	Target    string                 `json:"target"`
+	Delivered bool                   `json:"delivered"`
+	Response  map[string]interface{} `json:"response,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+	//Permanent - retrying the delivery will not succeed
+	Permanent bool `json:"-"`
+}
+
+//route - events matching the event type patterns and the predicate are sent to the targets
+type route struct {
+	eventTypes []string
+	predicate  string
+	equals     *string
+	targets    []target
+}
+
+//routing - the targets of an app and the routes selecting them
+type routing struct {
+	targets map[string]target
+	//all - targets in the order of the configuration, used if no routes are configured
+	all    []target
+	routes []route
+	//explicit - targets are configured, the results of the targets are reported
+	explicit bool
+}
+
+//newRouting - without targets all events are forwarded to the event publish url of the app
+func newRouting(app *config.AppConfig, eventTypes metrics.EventTypes) (*routing, error) {
+	if len(app.Targets) == 0 {
+		forwarder := NewEventForwarder(app.EventPublishURL, app.EventFormat, app.Limits.MaxConcurrentForwards, app.ForwardTimeout)
+		forwarder.eventTypes = eventTypes
+		return &routing{
+			targets: map[string]target{defaultTarget: forwarder},
+			all:     []target{forwarder},
+		}, nil
+	}
+
+	r := &routing{targets: map[string]target{}, explicit: true}
+	for i := range app.Targets {
+		targetConfig := &app.Targets[i]
+
+		var t target
+		var err error
+		if targetConfig.Type == config.TargetFile {
+			t, err = newFileTarget(targetConfig.Name, targetConfig.File, eventTypes)
+		} else {
+			t, err = newHTTPTarget(targetConfig, eventTypes)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		r.targets[targetConfig.Name] = t
+		r.all = append(r.all, t)
+	}
+
+	for _, routeConfig := range app.Routes {
+		current := route{
+			eventTypes: routeConfig.EventTypes,
+			predicate:  routeConfig.Predicate,
+			equals:     routeConfig.Equals,
+		}
+		for _, name := range routeConfig.Targets {
+			current.targets = append(current.targets, r.targets[name])
+		}
+		r.routes = append(r.routes, current)
+	}
+
+	return r, nil
+}
+
+//match - targets of all matching routes, every target at most once
+func (r *routing) match(event *KymaEvent) []target {
+	if len(r.routes) == 0 {
+		return r.all
+	}
+
+	var targets []target
+	seen := map[string]bool{}
+	for _, current := range r.routes {
+		if !current.matches(event) {
+			continue
+		}
+		for _, t := range current.targets {
+			if !seen[t.targetName()] {
+				seen[t.targetName()] = true
+				targets = append(targets, t)
+			}
+		}
+	}
+	return targets
+}
+
+func (r *route) matches(event *KymaEvent) bool {
+	if len(r.eventTypes) > 0 {
+		matched := false
+		for _, pattern := range r.eventTypes {
+			if ok, _ := path.Match(pattern, event.EventType); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return eventtype.Matches(string(event.Data), r.predicate, r.equals)
+}
+
+//forwardAll - forwards the event to all targets in parallel
+func forwardAll(event *KymaEvent, targets []target) []TargetResult {
+	results := make([]TargetResult, len(targets))
+
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, t target) {
+			defer wg.Done()
+
+			result := TargetResult{Target: t.targetName()}
+			resp, err := t.ForwardEvent(event)
+			result.Response = resp
+			if err != nil {
+				result.Error = err.Error()
+				result.Permanent = isPermanent(err)
+			} else {
+				result.Delivered = true
+			}
+			results[i] = result
+		}(i, t)
+	}
+	wg.Wait()
+
+	return results
+}
+
+//fileTarget - appends the events to a NDJSON file
+type fileTarget struct {
+	name       string
+	file       string
+	eventTypes metrics.EventTypes
+	lock       sync.Mutex
+}
+
+func newFileTarget(name string, file string, eventTypes metrics.EventTypes) (*fileTarget, error) {
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return nil, err
+	}
+	return &fileTarget{name: name, file: file, eventTypes: eventTypes}, nil
+}
+
+func (f *fileTarget) targetName() string {
+	return f.name
+}
+
+func (f *fileTarget) ForwardEvent(event *KymaEvent) (map[string]interface{}, error) {
+	start := time.Now()
+	err := f.write(event)
+	metrics.ObserveForward(f.name, f.eventTypes.Label(event.EventType), start, err)
+	return nil, err
+}
+
+func (f *fileTarget) write(event *KymaEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	file, err := os.OpenFile(f.file, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	//noinspection GoUnhandledErrorResult
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return file.Sync()
+}
@@ -0,0 +1,202 @@
+package events
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/config"
+)
+
+func TestRouting_Match(t *testing.T) {
+
+	paid := "paid"
+	r, err := newRouting(&config.AppConfig{
+		Targets: []config.TargetConfig{
+			{Name: "orders", Type: config.TargetKyma, URL: "http://localhost", Format: "legacy"},
+			{Name: "payments", Type: config.TargetHTTP, URL: "http://localhost", Format: "binary"},
+			{Name: "audit", Type: config.TargetFile, File: filepath.Join(os.TempDir(), "audit.ndjson")},
+		},
+		Routes: []config.RouteConfig{
+			{EventTypes: []string{"order.*"}, Targets: []string{"orders", "audit"}},
+			{EventTypes: []string{"order.updated"}, Predicate: "status", Equals: &paid, Targets: []string{"payments", "audit"}},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("routing creation failed: %s", err.Error())
+	}
+
+	tests := []struct {
+		eventType string
+		data      string
+		targets   []string
+	}{
+		{"order.created", `{"status":"paid"}`, []string{"orders", "audit"}},
+		{"order.updated", `{"status":"open"}`, []string{"orders", "audit"}},
+		{"order.updated", `{"status":"paid"}`, []string{"orders", "audit", "payments"}},
+		{"customer.created", `{}`, nil},
+	}
+
+	for _, test := range tests {
+		targets := r.match(&KymaEvent{EventType: test.eventType, Data: jsonString(test.data)})
+
+		var names []string
+		for _, t := range targets {
+			names = append(names, t.targetName())
+		}
+		if strings.Join(names, ",") != strings.Join(test.targets, ",") {
+			t.Errorf("%s %s: expected targets %v, got %v", test.eventType, test.data, test.targets, names)
+		}
+	}
+}
+
+func TestEventsHandler_Routing(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "routing")
+	defer os.RemoveAll(dir)
+
+	var authorization string
+	kyma := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.Write([]byte(`{"event-id":"x"}`))
+	}))
+	defer kyma.Close()
+
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("ce-type") == "order.cancelled" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("OK"))
+	}))
+	defer sink.Close()
+
+	auditFile := filepath.Join(dir, "audit", "events.ndjson")
+	app := &config.AppConfig{
+		Name:           "shop",
+		EventTypeQuery: "type",
+		EventFormat:    "legacy",
+		Targets: []config.TargetConfig{
+			{Name: "kyma", URL: kyma.URL, Auth: config.TargetAuth{BearerToken: "secret"}},
+			{Name: "sink", Type: config.TargetHTTP, URL: sink.URL, Format: "binary"},
+			{Name: "audit", Type: config.TargetFile, File: auditFile},
+		},
+		Routes: []config.RouteConfig{
+			{EventTypes: []string{"order.*"}, Targets: []string{"kyma", "sink"}},
+			{Targets: []string{"audit"}, Predicate: "audit"},
+		},
+		Batch: config.BatchConfig{Concurrency: 1},
+	}
+	k, err := NewKymaEventProcesser(app, nil, nil)
+	if err != nil {
+		t.Fatalf("processer creation failed: %s", err.Error())
+	}
+
+	send := func(body string) (int, *Result) {
+		recorder := httptest.NewRecorder()
+		k.EventsHandler(recorder, httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(body)))
+
+		var result Result
+		_ = json.Unmarshal(recorder.Body.Bytes(), &result)
+		return recorder.Code, &result
+	}
+
+	status, result := send(`{"type":"order.created","audit":true}`)
+	if status != http.StatusOK || len(result.Targets) != 3 {
+		t.Fatalf("expected event to be published to 3 targets, got %d %+v", status, result)
+	}
+	for _, targetResult := range result.Targets {
+		if !targetResult.Delivered {
+			t.Errorf("expected delivery to %s, got %+v", targetResult.Target, targetResult)
+		}
+	}
+	if authorization != "Bearer secret" {
+		t.Errorf("expected the credentials of the target, got %q", authorization)
+	}
+	if data, _ := ioutil.ReadFile(auditFile); !strings.Contains(string(data), `"event-type":"order.created"`) {
+		t.Errorf("expected event in the file target, got %q", data)
+	}
+
+	status, result = send(`{"type":"order.cancelled"}`)
+	if status != http.StatusBadGateway || len(result.Targets) != 2 || !result.Targets[0].Delivered || result.Targets[1].Delivered {
+		t.Errorf("expected the delivery to the sink to fail, got %d %+v", status, result)
+	}
+
+	status, _ = send(`{"type":"customer.created"}`)
+	if status != http.StatusUnprocessableEntity {
+		t.Errorf("expected unrouted event to be rejected, got %d", status)
+	}
+}
+
+func TestEventsHandler_RoutingRetry(t *testing.T) {
+	var lock sync.Mutex
+	received := map[string]int{}
+	sinkAvailable := false
+
+	newTarget := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			defer lock.Unlock()
+			if name == "sink" && !sinkAvailable {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			received[name]++
+			w.Write([]byte(`{"event-id":"x"}`))
+		}))
+	}
+	kyma := newTarget("kyma")
+	defer kyma.Close()
+	sink := newTarget("sink")
+	defer sink.Close()
+
+	app := &config.AppConfig{
+		Name:           "shop",
+		EventTypeQuery: "type",
+		EventFormat:    "legacy",
+		Dedup:          config.DedupConfig{Enabled: true, Header: "X-Delivery-Id", TTL: time.Hour, MaxEntries: 100},
+		Targets: []config.TargetConfig{
+			{Name: "kyma", URL: kyma.URL, Format: "legacy"},
+			{Name: "sink", URL: sink.URL, Format: "legacy"},
+		},
+		Batch: config.BatchConfig{Concurrency: 1},
+	}
+	k, err := NewKymaEventProcesser(app, nil, nil)
+	if err != nil {
+		t.Fatalf("processer creation failed: %s", err.Error())
+	}
+
+	send := func() int {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(`{"type":"order.created"}`))
+		req.Header.Set("X-Delivery-Id", "delivery-1")
+		k.EventsHandler(recorder, req)
+		return recorder.Code
+	}
+
+	if status := send(); status != http.StatusBadGateway {
+		t.Errorf("expected the failed delivery to the sink to be a bad gateway, got %d", status)
+	}
+
+	lock.Lock()
+	sinkAvailable = true
+	lock.Unlock()
+	if status := send(); status != http.StatusOK {
+		t.Errorf("expected the retry to be published, got %d", status)
+	}
+	if status := send(); status != http.StatusOK {
+		t.Errorf("expected the duplicate to be acknowledged, got %d", status)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if received["kyma"] != 1 || received["sink"] != 1 {
+		t.Errorf("expected every target to receive the event once, got %v", received)
+	}
+}
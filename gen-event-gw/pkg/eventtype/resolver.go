@@ -0,0 +1,210 @@
+package eventtype
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+const defaultVersion = "v1"
+
+var placeholderRegex = regexp.MustCompile(`\{\{\s*([^{}]+?)\s*\}\}`)
+
+//ruleConfig - a single entry of the rule file
+type ruleConfig struct {
+	Predicate    string  `json:"predicate"`
+	Equals       *string `json:"equals,omitempty"`
+	TypeTemplate string  `json:"typeTemplate"`
+	Version      string  `json:"version"`
+	FallbackType string  `json:"fallbackType"`
+}
+
+//templatePart - either a literal or a gjson path to be resolved against the event
+type templatePart struct {
+	literal string
+	path    string
+}
+
+type rule struct {
+	predicate    string
+	equals       *string
+	template     []templatePart
+	version      string
+	fallbackType string
+}
+
+//Resolver - determines event type and version by evaluating an ordered list of rules
+type Resolver struct {
+	rules []rule
+}
+
+//New - reads the rule file and compiles the contained rules
+func New(file string) (*Resolver, error) {
+	configData, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading event type rules: %s", err.Error())
+	}
+
+	var ruleConfigList []ruleConfig
+	if err := json.Unmarshal(configData, &ruleConfigList); err != nil {
+		return nil, fmt.Errorf("error in event type rules json: %s", err.Error())
+	}
+
+	if len(ruleConfigList) == 0 {
+		return nil, fmt.Errorf("event type rules %s do not contain any rule", file)
+	}
+
+	rules := make([]rule, len(ruleConfigList))
+	for i, currentConfig := range ruleConfigList {
+		if currentConfig.TypeTemplate == "" && currentConfig.FallbackType == "" {
+			return nil, fmt.Errorf("rule %d needs either a \"typeTemplate\" or a \"fallbackType\"", i)
+		}
+		rules[i] = rule{
+			predicate:    currentConfig.Predicate,
+			equals:       currentConfig.Equals,
+			template:     compileTemplate(currentConfig.TypeTemplate),
+			version:      versionOrDefault(currentConfig.Version),
+			fallbackType: currentConfig.FallbackType,
+		}
+	}
+
+	return &Resolver{rules: rules}, nil
+}
+
+//NewFromQuery - single rule resolver using the value of the query as event type
+func NewFromQuery(query string) *Resolver {
+	return &Resolver{
+		rules: []rule{{
+			predicate: query,
+			template:  []templatePart{{path: query}},
+			version:   defaultVersion,
+		}},
+	}
+}
+
+//Resolve - returns type and version of the first matching rule
+func (r *Resolver) Resolve(data string) (eventType string, eventVersion string, err error) {
+	for _, currentRule := range r.rules {
+		if !currentRule.matches(data) {
+			continue
+		}
+
+		eventType = currentRule.render(data)
+		if eventType == "" {
+			eventType = currentRule.fallbackType
+		}
+		if eventType != "" {
+			return eventType, currentRule.version, nil
+		}
+	}
+
+	return "", "", fmt.Errorf("no event type rule matched the received data")
+}
+
+//Types - the event types the rules resolve to independent of the data, i.e. templates without placeholders
+//and fallback types
+func (r *Resolver) Types() []string {
+	var types []string
+	for _, currentRule := range r.rules {
+		if currentRule.fallbackType != "" {
+			types = append(types, currentRule.fallbackType)
+		}
+
+		var sb strings.Builder
+		literal := len(currentRule.template) > 0
+		for _, part := range currentRule.template {
+			if part.path != "" {
+				literal = false
+				break
+			}
+			sb.WriteString(part.literal)
+		}
+		if literal {
+			types = append(types, sb.String())
+		}
+	}
+	return types
+}
+
+func (r *rule) matches(data string) bool {
+	return Matches(data, r.predicate, r.equals)
+}
+
+//Matches - an empty predicate always matches, otherwise the value of the gjson path has to equal the
+//expected value or, without an expected value, has to be truthy
+func Matches(data string, predicate string, equals *string) bool {
+	if predicate == "" {
+		return true
+	}
+
+	value := gjson.Get(data, predicate)
+	if !value.Exists() {
+		return false
+	}
+
+	if equals != nil {
+		return value.String() == *equals
+	}
+
+	switch value.Type {
+	case gjson.Null, gjson.False:
+		return false
+	case gjson.String:
+		return value.Str != ""
+	}
+
+	return true
+}
+
+//render - builds the event type, an empty result signals that a placeholder could not be resolved
+func (r *rule) render(data string) string {
+	if len(r.template) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	for _, part := range r.template {
+		if part.path == "" {
+			sb.WriteString(part.literal)
+			continue
+		}
+
+		value := gjson.Get(data, part.path).String()
+		if value == "" {
+			return ""
+		}
+		sb.WriteString(value)
+	}
+
+	return sb.String()
+}
+
+func compileTemplate(template string) []templatePart {
+	var parts []templatePart
+
+	last := 0
+	for _, loc := range placeholderRegex.FindAllStringSubmatchIndex(template, -1) {
+		if loc[0] > last {
+			parts = append(parts, templatePart{literal: template[last:loc[0]]})
+		}
+		parts = append(parts, templatePart{path: template[loc[2]:loc[3]]})
+		last = loc[1]
+	}
+
+	if last < len(template) {
+		parts = append(parts, templatePart{literal: template[last:]})
+	}
+
+	return parts
+}
+
+func versionOrDefault(version string) string {
+	if version == "" {
+		return defaultVersion
+	}
+	return version
+}
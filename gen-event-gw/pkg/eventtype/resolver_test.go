@@ -0,0 +1,101 @@
+package eventtype
+
+import "testing"
+
+func TestNew(t *testing.T) {
+
+	_, err := New("testdata/rules_valid.json")
+
+	if err != nil {
+		t.Errorf("Reading valid rules failed: %s", err.Error())
+	}
+
+	_, err = New("testdata/rules_invalid.json")
+
+	if err == nil {
+		t.Errorf("Reading invalid rules should fail")
+	}
+
+	_, err = New("testdata/does_not_exist.json")
+
+	if err == nil {
+		t.Errorf("Reading missing rules should fail")
+	}
+}
+
+func TestResolve(t *testing.T) {
+
+	resolver, err := New("testdata/rules_valid.json")
+	if err != nil {
+		t.Fatalf("Reading valid rules failed: %s", err.Error())
+	}
+
+	tests := []struct {
+		name            string
+		data            string
+		expectedType    string
+		expectedVersion string
+		expectError     bool
+	}{
+		{"template", `{"order":{"status":"created"}}`, "order.created", "v2", false},
+		{"empty predicate value", `{"order":{"status":""},"kind":"invoice","action":"paid"}`, "invoice.paid", "v1", false},
+		{"equals", `{"kind":"invoice","action":"paid"}`, "invoice.paid", "v1", false},
+		{"fallback", `{"kind":"invoice"}`, "invoice.unknown", "v1", false},
+		{"equals mismatch", `{"kind":"credit"}`, "", "", true},
+		{"wildcard", `{"Root":{"@xmlns":"urn:fieldglass:worker"}}`, "urn:fieldglass:worker", "v1", false},
+		{"no match", `{"param":"xyz"}`, "", "", true},
+	}
+
+	for _, test := range tests {
+		eventType, eventVersion, err := resolver.Resolve(test.data)
+
+		if test.expectError {
+			if err == nil {
+				t.Errorf("%s: error expected, but resolved %q", test.name, eventType)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.name, err.Error())
+			continue
+		}
+
+		if eventType != test.expectedType || eventVersion != test.expectedVersion {
+			t.Errorf("%s: expected %s/%s, got %s/%s", test.name, test.expectedType, test.expectedVersion, eventType, eventVersion)
+		}
+	}
+}
+
+func TestNewFromQuery(t *testing.T) {
+
+	resolver := NewFromQuery("param2.eventtype")
+
+	eventType, eventVersion, err := resolver.Resolve(`{"param1":"xyz","param2": {"eventtype": "myTestEvent"}}`)
+
+	if err != nil || eventType != "myTestEvent" || eventVersion != "v1" {
+		t.Errorf("Event type not correctly resolved: %s/%s", eventType, eventVersion)
+	}
+
+	_, _, err = resolver.Resolve(`{"param1":"xyz"}`)
+
+	if err == nil {
+		t.Error("Error expected for missing event type")
+	}
+}
+
+func TestTypes(t *testing.T) {
+
+	resolver, err := New("testdata/rules_valid.json")
+	if err != nil {
+		t.Fatalf("Reading valid rules failed: %s", err.Error())
+	}
+
+	if types := resolver.Types(); len(types) != 1 || types[0] != "invoice.unknown" {
+		t.Errorf("Expected the fallback type only, got %v", types)
+	}
+
+	if types := NewFromQuery("param2.eventtype").Types(); len(types) != 0 {
+		t.Errorf("Expected no types of a query, got %v", types)
+	}
+}
@@ -0,0 +1,189 @@
+package limit
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/auth"
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/metrics"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
+)
+
+const (
+	//KeyIP - requests are limited per client ip
+	KeyIP = "ip"
+	//KeyPrincipal - requests are limited per authenticated principal
+	KeyPrincipal = "principal"
+
+	//sweepInterval - interval of removing the buckets of idle sources
+	sweepInterval = time.Minute
+)
+
+//BodySize - rejects requests with a body larger than maxSize with 413
+//The body is buffered, so the following handlers can read it again
+func BodySize(maxSize int64, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > maxSize {
+			rejectBodySize(w, maxSize)
+			return
+		}
+
+		//chunked bodies do not announce their length
+		body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxSize+1))
+		if err != nil {
+			log.Println(err)
+			http.Error(w, "Could not read data", http.StatusBadRequest)
+			return
+		}
+		if int64(len(body)) > maxSize {
+			rejectBodySize(w, maxSize)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func rejectBodySize(w http.ResponseWriter, maxSize int64) {
+	log.Printf("Request body exceeds the maximum size of %d bytes", maxSize)
+	metrics.RequestsLimited.WithLabelValues(metrics.LimitBodySize).Inc()
+	w.Header().Set("Connection", "close")
+	http.Error(w, fmt.Sprintf("Request body exceeds the maximum size of %d bytes", maxSize), http.StatusRequestEntityTooLarge)
+}
+
+//Limiter - token bucket rate limit of each source
+type Limiter struct {
+	name  string
+	limit rate.Limit
+	burst int
+	idle  time.Duration
+
+	lock      sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+//NewLimiter - allows perSecond requests of each source with bursts of up to burst requests, the name is used in metrics
+func NewLimiter(name string, perSecond float64, burst int) *Limiter {
+	//a bucket that was not used for the time it takes to refill is the same as a new one
+	idle := time.Duration(float64(burst) / perSecond * float64(time.Second))
+	if idle < sweepInterval {
+		idle = sweepInterval
+	}
+
+	return &Limiter{
+		name:      name,
+		limit:     rate.Limit(perSecond),
+		burst:     burst,
+		idle:      idle,
+		buckets:   map[string]*bucket{},
+		lastSweep: time.Now(),
+	}
+}
+
+//Allow - takes a token of the source, returns the time until the next token is available if there is none left
+func (l *Limiter) Allow(source string) (bool, time.Duration) {
+	now := time.Now()
+
+	l.lock.Lock()
+	if now.Sub(l.lastSweep) > sweepInterval {
+		l.sweep(now)
+	}
+	b, ok := l.buckets[source]
+	if !ok {
+		b = &bucket{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.buckets[source] = b
+	}
+	b.lastSeen = now
+	l.lock.Unlock()
+
+	reservation := b.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return false, time.Second
+	}
+	delay := reservation.DelayFrom(now)
+	if delay == 0 {
+		return true, 0
+	}
+	//rejected requests do not consume a token
+	reservation.CancelAt(now)
+	return false, delay
+}
+
+//sweep - removes the buckets of sources that have been idle long enough to be full again
+func (l *Limiter) sweep(now time.Time) {
+	for source, b := range l.buckets {
+		if now.Sub(b.lastSeen) > l.idle {
+			delete(l.buckets, source)
+		}
+	}
+	l.lastSweep = now
+}
+
+//Middleware - rejects requests of sources without tokens left with 429 and Retry-After
+func Middleware(limiter *Limiter, source func(r *http.Request) string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := source(r)
+		if ok, delay := limiter.Allow(key); !ok {
+			log.Printf("Rate limit %s exceeded by %q", limiter.name, key)
+			metrics.RequestsLimited.WithLabelValues(limiter.name).Inc()
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+//Global - all requests share one bucket
+func Global(r *http.Request) string {
+	return ""
+}
+
+//ClientIP - the last address of the header, as appended by the ingress, or the remote address if the header is empty
+func ClientIP(header string) func(r *http.Request) string {
+	return func(r *http.Request) string {
+		if header != "" {
+			if values := r.Header[http.CanonicalHeaderKey(header)]; len(values) > 0 {
+				addresses := strings.Split(values[len(values)-1], ",")
+				if address := strings.TrimSpace(addresses[len(addresses)-1]); address != "" {
+					return address
+				}
+			}
+		}
+
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			return r.RemoteAddr
+		}
+		return host
+	}
+}
+
+//Principal - the principal authenticated by the auth middleware, the client ip for requests without principal
+func Principal(header string) func(r *http.Request) string {
+	clientIP := ClientIP(header)
+	return func(r *http.Request) string {
+		if principal := auth.Principal(r); principal != "" {
+			return principal
+		}
+		return clientIP(r)
+	}
+}
@@ -0,0 +1,108 @@
+package limit
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBodySize(t *testing.T) {
+
+	handler := BodySize(10, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(body)
+	}))
+
+	tests := []struct {
+		name          string
+		body          string
+		contentLength int64
+		status        int
+	}{
+		{"small body", "0123456789", 10, http.StatusOK},
+		{"announced large body", "01234567890", 11, http.StatusRequestEntityTooLarge},
+		{"chunked large body", "01234567890", -1, http.StatusRequestEntityTooLarge},
+		{"chunked small body", "012", -1, http.StatusOK},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/events", strings.NewReader(test.body))
+		r.ContentLength = test.contentLength
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, r)
+
+		if recorder.Code != test.status {
+			t.Errorf("%s: expected status %d, got %d", test.name, test.status, recorder.Code)
+		}
+		if test.status == http.StatusOK && recorder.Body.String() != test.body {
+			t.Errorf("%s: expected body %q, got %q", test.name, test.body, recorder.Body.String())
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+
+	limiter := NewLimiter("test", 1, 2)
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := limiter.Allow("a"); !ok {
+			t.Errorf("request %d within the burst should be allowed", i)
+		}
+	}
+	ok, delay := limiter.Allow("a")
+	if ok {
+		t.Errorf("request exceeding the burst should be rejected")
+	}
+	if delay <= 0 || delay > time.Second {
+		t.Errorf("unexpected delay %s", delay)
+	}
+
+	if ok, _ := limiter.Allow("b"); !ok {
+		t.Errorf("sources should be limited independently")
+	}
+
+	//idle buckets are full again and can be removed
+	limiter.sweep(time.Now().Add(2 * sweepInterval))
+	if len(limiter.buckets) != 0 {
+		t.Errorf("expected idle buckets to be removed, got %d", len(limiter.buckets))
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+
+	handler := Middleware(NewLimiter("test", 0.5, 1), ClientIP("X-Forwarded-For"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	request := func(remoteAddr string, forwardedFor string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/events", nil)
+		r.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			r.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, r)
+		return recorder
+	}
+
+	if recorder := request("10.0.0.1:1234", ""); recorder.Code != http.StatusOK {
+		t.Errorf("expected first request to be allowed, got %d", recorder.Code)
+	}
+
+	recorder := request("10.0.0.1:5678", "")
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status %d, got %d", http.StatusTooManyRequests, recorder.Code)
+	}
+	if recorder.Header().Get("Retry-After") != "2" {
+		t.Errorf("expected Retry-After 2, got %q", recorder.Header().Get("Retry-After"))
+	}
+
+	//the ingress appends the client address to the header
+	if recorder := request("10.0.0.1:1234", "1.2.3.4, 192.168.0.1"); recorder.Code != http.StatusOK {
+		t.Errorf("expected request of another client to be allowed, got %d", recorder.Code)
+	}
+	if recorder := request("10.0.0.2:1234", "5.6.7.8, 192.168.0.1"); recorder.Code != http.StatusTooManyRequests {
+		t.Errorf("expected the last address of the header to be the source, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,192 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	//OutcomeSuccess - event forwarded
+	OutcomeSuccess = "success"
+	//OutcomeError - forwarding failed
+	OutcomeError = "error"
+
+	//ReasonReadError - request body could not be read
+	ReasonReadError = "read_error"
+	//ReasonConversion - request body could not be converted to json
+	ReasonConversion = "conversion"
+	//ReasonTypeResolution - no event type could be determined
+	ReasonTypeResolution = "type_resolution"
+	//ReasonTransformation - event data could not be transformed
+	ReasonTransformation = "transformation"
+	//ReasonPersistence - event could not be stored in the outbox
+	ReasonPersistence = "persistence"
+	//ReasonSchema - event data does not match the schema of the event type
+	ReasonSchema = "schema"
+	//ReasonRouting - no route matched the event
+	ReasonRouting = "routing"
+	//ReasonInFlight - a copy of the event with the same delivery id is being processed
+	ReasonInFlight = "in_flight"
+
+	//LimitBodySize - request body exceeded the maximum size
+	LimitBodySize = "body_size"
+	//LimitGlobal - rate limit shared by all requests
+	LimitGlobal = "global"
+	//LimitSource - rate limit of a single client ip or principal
+	LimitSource = "source"
+
+	//OtherEventType - label of the event types that are not listed in the configuration
+	OtherEventType = "other"
+
+	eventTypeLabel    = "event_type"
+	limitLabel        = "limit"
+	outcomeLabel      = "outcome"
+	reasonLabel       = "reason"
+	ruleLabel         = "rule"
+	targetLabel       = "target"
+	responseCodeLabel = "responseCode"
+)
+
+var (
+	//EventsReceived - events received on the events endpoint
+	EventsReceived = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "events_received_total",
+		Help: "The total number of received events",
+	})
+
+	//EventsRejected - received events that were not accepted
+	EventsRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "events_rejected_total",
+		Help: "The total number of rejected events by reason",
+	}, []string{reasonLabel})
+
+	//EventsDuplicate - redelivered events that were acknowledged without forwarding
+	EventsDuplicate = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "events_duplicate_total",
+		Help: "The total number of redelivered events that were not forwarded again",
+	})
+
+	//RequestsLimited - requests rejected because they exceeded a limit
+	RequestsLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "requests_limited_total",
+		Help: "The total number of requests rejected by limit",
+	}, []string{limitLabel})
+
+	//TypeResolutionFailed - received events without a resolvable event type
+	TypeResolutionFailed = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "event_type_resolution_failed_total",
+		Help: "The total number of events for which no event type could be determined",
+	})
+
+	//SchemaViolations - violated schema rules of received events
+	SchemaViolations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "schema_violations_total",
+		Help: "The total number of schema violations by event type and rule",
+	}, []string{eventTypeLabel, ruleLabel})
+
+	eventsForwarded = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "events_forwarded_total",
+		Help: "The total number of forwarded events by target, event type and outcome",
+	}, []string{targetLabel, eventTypeLabel, outcomeLabel})
+
+	forwardDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "event_forward_duration_seconds",
+		Help:    "The latency of forwarding events to the targets",
+		Buckets: prometheus.DefBuckets,
+	}, []string{targetLabel, eventTypeLabel, outcomeLabel})
+
+	//InFlightForwards - events currently being forwarded
+	InFlightForwards = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "in_flight_forwards",
+		Help: "The number of events currently being forwarded",
+	})
+
+	//WaitingForwards - events waiting for a free forwarding slot
+	WaitingForwards = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "waiting_forwards",
+		Help: "The number of events waiting for the concurrency limit of forwarding",
+	})
+
+	httpCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "requests_processed_total",
+		Help: "The total number of processed requests",
+	}, []string{responseCodeLabel})
+
+	serverResponseTime = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "server_response_time_seconds",
+		Help:    "The server response times",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	inFlightRequests = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "in_flight_requests",
+		Help: "The number of requests currently active",
+	})
+)
+
+//EventTypes - event types listed in the configuration of an app. The event_type labels are bound to them,
+//so event types taken from the payloads do not create an unbounded number of series
+type EventTypes map[string]bool
+
+//NewEventTypes - set of the listed event types, patterns containing wildcards are left out
+func NewEventTypes(lists ...[]string) EventTypes {
+	eventTypes := EventTypes{}
+	for _, list := range lists {
+		for _, eventType := range list {
+			if eventType != "" && !strings.ContainsAny(eventType, `*?[\`) {
+				eventTypes[eventType] = true
+			}
+		}
+	}
+	return eventTypes
+}
+
+//Label - the event type if it is listed, other otherwise
+func (e EventTypes) Label(eventType string) string {
+	if e[eventType] {
+		return eventType
+	}
+	return OtherEventType
+}
+
+//ObserveForward - records outcome and latency of forwarding an event to a target, the event type has to be
+//a bound label, see EventTypes
+func ObserveForward(target string, eventType string, start time.Time, err error) {
+	outcome := OutcomeSuccess
+	if err != nil {
+		outcome = OutcomeError
+	}
+
+	eventsForwarded.WithLabelValues(target, eventType, outcome).Inc()
+	forwardDuration.WithLabelValues(target, eventType, outcome).Observe(time.Since(start).Seconds())
+}
+
+//Middleware - records response codes, response times and in-flight requests
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		inFlightRequests.Inc()
+		next.ServeHTTP(recorder, r)
+		inFlightRequests.Dec()
+
+		httpCalls.WithLabelValues(strconv.Itoa(recorder.status/100) + "xx").Inc()
+		serverResponseTime.Observe(time.Since(start).Seconds())
+	})
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
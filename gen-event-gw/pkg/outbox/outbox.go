@@ -0,0 +1,361 @@
+package outbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	pendingDir     = "pending"
+	deadLetterFile = "dead-letter.ndjson"
+	entrySuffix    = ".json"
+	tmpSuffix      = ".tmp"
+)
+
+//Config - settings of the outbox
+type Config struct {
+	Dir            string
+	Workers        int
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+//DeliverFunc - delivers a persisted payload, an error triggers a retry
+type DeliverFunc func(payload []byte) error
+
+//permanentError - marks errors that must not be retried
+type permanentError struct {
+	err error
+}
+
+func (p *permanentError) Error() string {
+	return p.err.Error()
+}
+
+func (p *permanentError) Unwrap() error {
+	return p.err
+}
+
+//Permanent - wraps an error so the entry is moved to the dead letter file without further retries
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+//entry - persisted representation of an event waiting for delivery
+type entry struct {
+	ID          string          `json:"id"`
+	Created     time.Time       `json:"created"`
+	Attempts    int             `json:"attempts"`
+	NextAttempt time.Time       `json:"nextAttempt"`
+	LastError   string          `json:"lastError,omitempty"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+//Outbox - write ahead store for events with background delivery
+type Outbox struct {
+	config  Config
+	deliver DeliverFunc
+
+	queue   chan string
+	stop    chan struct{}
+	workers sync.WaitGroup
+
+	//ids of the entries that are persisted and scheduled, each id is scheduled only once
+	pendingLock sync.Mutex
+	pending     map[string]struct{}
+
+	deadLetterLock sync.Mutex
+	randLock       sync.Mutex
+	rand           *rand.Rand
+}
+
+//New - creates the directory layout of the outbox
+func New(config Config) (*Outbox, error) {
+	if config.Dir == "" {
+		return nil, fmt.Errorf("outbox directory is not configured")
+	}
+	if config.Workers < 1 {
+		return nil, fmt.Errorf("outbox needs at least one worker, got %d", config.Workers)
+	}
+	if config.MaxAttempts < 1 {
+		return nil, fmt.Errorf("outbox needs at least one delivery attempt, got %d", config.MaxAttempts)
+	}
+	if config.InitialBackoff <= 0 || config.MaxBackoff < config.InitialBackoff {
+		return nil, fmt.Errorf("invalid outbox backoff %s - %s", config.InitialBackoff, config.MaxBackoff)
+	}
+
+	if err := os.MkdirAll(filepath.Join(config.Dir, pendingDir), 0700); err != nil {
+		return nil, fmt.Errorf("error creating outbox directory: %s", err.Error())
+	}
+
+	return &Outbox{
+		config:  config,
+		queue:   make(chan string, config.Workers),
+		stop:    make(chan struct{}),
+		pending: make(map[string]struct{}),
+		rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, nil
+}
+
+//Start - starts the workers and schedules entries left over from a previous run
+func (o *Outbox) Start(deliver DeliverFunc) error {
+	o.deliver = deliver
+
+	for i := 0; i < o.config.Workers; i++ {
+		o.workers.Add(1)
+		go o.work()
+	}
+
+	files, err := ioutil.ReadDir(filepath.Join(o.config.Dir, pendingDir))
+	if err != nil {
+		return fmt.Errorf("error reading outbox directory: %s", err.Error())
+	}
+
+	recovered := 0
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), entrySuffix) {
+			continue
+		}
+		id := strings.TrimSuffix(file.Name(), entrySuffix)
+		e, err := o.read(id)
+		if err != nil {
+			log.Printf("Outbox entry %s could not be read - %s", id, err.Error())
+			continue
+		}
+		if !o.markPending(id) {
+			continue
+		}
+		o.schedule(id, e.NextAttempt)
+		recovered++
+	}
+
+	if recovered > 0 {
+		log.Printf("Outbox recovered %d pending events", recovered)
+	}
+
+	return nil
+}
+
+//Stop - stops scheduling and waits for the running deliveries, pending entries stay on disk
+func (o *Outbox) Stop() {
+	close(o.stop)
+	o.workers.Wait()
+}
+
+//Put - persists the payload, once Put returns the payload survives restarts.
+//An id that is still pending is not stored again, the pending entry delivers it
+func (o *Outbox) Put(id string, payload []byte) error {
+	if strings.ContainsAny(id, `/\`) || id == "" {
+		return fmt.Errorf("invalid outbox entry id %q", id)
+	}
+
+	if !o.markPending(id) {
+		log.Printf("Outbox entry %s is already pending, skipping", id)
+		return nil
+	}
+
+	now := time.Now()
+	e := &entry{
+		ID:          id,
+		Created:     now,
+		NextAttempt: now,
+		Payload:     payload,
+	}
+
+	if err := o.write(e); err != nil {
+		o.unmarkPending(id)
+		return err
+	}
+
+	o.schedule(id, now)
+	return nil
+}
+
+//markPending - returns false if the id is already pending
+func (o *Outbox) markPending(id string) bool {
+	o.pendingLock.Lock()
+	defer o.pendingLock.Unlock()
+
+	if _, ok := o.pending[id]; ok {
+		return false
+	}
+	o.pending[id] = struct{}{}
+	return true
+}
+
+func (o *Outbox) unmarkPending(id string) {
+	o.pendingLock.Lock()
+	defer o.pendingLock.Unlock()
+
+	delete(o.pending, id)
+}
+
+func (o *Outbox) schedule(id string, at time.Time) {
+	time.AfterFunc(time.Until(at), func() {
+		select {
+		case o.queue <- id:
+		case <-o.stop:
+		}
+	})
+}
+
+func (o *Outbox) work() {
+	defer o.workers.Done()
+
+	for {
+		select {
+		case id := <-o.queue:
+			o.process(id)
+		case <-o.stop:
+			return
+		}
+	}
+}
+
+func (o *Outbox) process(id string) {
+	e, err := o.read(id)
+	if err != nil {
+		log.Printf("Outbox entry %s could not be read - %s", id, err.Error())
+		o.unmarkPending(id)
+		return
+	}
+
+	err = o.deliver(e.Payload)
+	if err == nil {
+		if err := os.Remove(o.path(id)); err != nil {
+			log.Printf("Delivered outbox entry %s could not be removed - %s", id, err.Error())
+		}
+		o.unmarkPending(id)
+		return
+	}
+
+	e.Attempts++
+	e.LastError = err.Error()
+
+	var permanent *permanentError
+	if errors.As(err, &permanent) || e.Attempts >= o.config.MaxAttempts {
+		log.Printf("Outbox entry %s failed after %d attempts, moving to dead letter file - %s", id, e.Attempts, err.Error())
+		if err := o.deadLetter(e); err != nil {
+			//the entry stays pending and is moved again later
+			log.Printf("Outbox entry %s could not be moved to the dead letter file, retrying - %s", id, err.Error())
+			o.schedule(id, time.Now().Add(o.config.MaxBackoff))
+			return
+		}
+		o.unmarkPending(id)
+		return
+	}
+
+	e.NextAttempt = time.Now().Add(o.backoff(e.Attempts))
+	log.Printf("Outbox entry %s failed (attempt %d), retrying at %s - %s", id, e.Attempts, e.NextAttempt.Format(time.RFC3339), err.Error())
+
+	if err := o.write(e); err != nil {
+		log.Printf("Outbox entry %s could not be updated - %s", id, err.Error())
+	}
+	o.schedule(id, e.NextAttempt)
+}
+
+//backoff - exponential backoff capped at the max backoff with equal jitter
+func (o *Outbox) backoff(attempts int) time.Duration {
+	backoff := o.config.InitialBackoff
+	for i := 1; i < attempts && backoff < o.config.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > o.config.MaxBackoff {
+		backoff = o.config.MaxBackoff
+	}
+
+	o.randLock.Lock()
+	jitter := time.Duration(o.rand.Int63n(int64(backoff)/2 + 1))
+	o.randLock.Unlock()
+
+	return backoff/2 + jitter
+}
+
+//deadLetter - appends the entry to the dead letter file and removes it from the pending entries
+func (o *Outbox) deadLetter(e *entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("error serializing outbox entry: %s", err.Error())
+	}
+
+	o.deadLetterLock.Lock()
+	defer o.deadLetterLock.Unlock()
+
+	file, err := os.OpenFile(filepath.Join(o.config.Dir, deadLetterFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("error opening dead letter file: %s", err.Error())
+	}
+	//noinspection GoUnhandledErrorResult
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("error writing dead letter file: %s", err.Error())
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("error syncing dead letter file: %s", err.Error())
+	}
+
+	if err := os.Remove(o.path(e.ID)); err != nil {
+		log.Printf("Dead outbox entry %s could not be removed - %s", e.ID, err.Error())
+	}
+	return nil
+}
+
+func (o *Outbox) path(id string) string {
+	return filepath.Join(o.config.Dir, pendingDir, id+entrySuffix)
+}
+
+func (o *Outbox) read(id string) (*entry, error) {
+	data, err := ioutil.ReadFile(o.path(id))
+	if err != nil {
+		return nil, err
+	}
+
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+//write - writes to a temporary file first, so readers never see partial entries
+func (o *Outbox) write(e *entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("error serializing outbox entry: %s", err.Error())
+	}
+
+	tmpPath := o.path(e.ID) + tmpSuffix
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("error creating outbox entry: %s", err.Error())
+	}
+
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("error writing outbox entry: %s", err.Error())
+	}
+
+	if err := os.Rename(tmpPath, o.path(e.ID)); err != nil {
+		return fmt.Errorf("error storing outbox entry: %s", err.Error())
+	}
+	return nil
+}
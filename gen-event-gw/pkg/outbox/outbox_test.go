@@ -0,0 +1,183 @@
+package outbox
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestOutbox(t *testing.T, dir string) *Outbox {
+	o, err := New(Config{
+		Dir:            dir,
+		Workers:        2,
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("outbox creation failed: %s", err.Error())
+	}
+	return o
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func pendingEntries(dir string) int {
+	files, _ := ioutil.ReadDir(filepath.Join(dir, pendingDir))
+	return len(files)
+}
+
+func TestNew(t *testing.T) {
+
+	_, err := New(Config{Dir: "", Workers: 1, MaxAttempts: 1, InitialBackoff: time.Second, MaxBackoff: time.Second})
+	if err == nil {
+		t.Error("missing directory should be rejected")
+	}
+
+	_, err = New(Config{Dir: "x", Workers: 1, MaxAttempts: 1, InitialBackoff: time.Minute, MaxBackoff: time.Second})
+	if err == nil {
+		t.Error("max backoff lower than initial backoff should be rejected")
+	}
+}
+
+func TestOutbox_Deliver(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "outbox")
+	defer os.RemoveAll(dir)
+
+	var lock sync.Mutex
+	attempts := map[string]int{}
+
+	o := newTestOutbox(t, dir)
+	err := o.Start(func(payload []byte) error {
+		lock.Lock()
+		defer lock.Unlock()
+		attempts[string(payload)]++
+		switch {
+		case string(payload) == `"flaky"` && attempts[string(payload)] < 2:
+			return fmt.Errorf("temporary failure")
+		case string(payload) == `"poison"`:
+			return fmt.Errorf("always fails")
+		case string(payload) == `"rejected"`:
+			return Permanent(fmt.Errorf("rejected by event bus"))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("outbox start failed: %s", err.Error())
+	}
+	defer o.Stop()
+
+	for _, id := range []string{"ok", "flaky", "poison", "rejected"} {
+		if err := o.Put(id, []byte(`"`+id+`"`)); err != nil {
+			t.Fatalf("put failed: %s", err.Error())
+		}
+	}
+
+	waitFor(t, func() bool { return pendingEntries(dir) == 0 })
+
+	lock.Lock()
+	defer lock.Unlock()
+	if attempts[`"ok"`] != 1 || attempts[`"flaky"`] != 2 || attempts[`"poison"`] != 3 || attempts[`"rejected"`] != 1 {
+		t.Errorf("unexpected delivery attempts %v", attempts)
+	}
+
+	deadLetters, err := ioutil.ReadFile(filepath.Join(dir, deadLetterFile))
+	if err != nil {
+		t.Fatalf("dead letter file missing: %s", err.Error())
+	}
+	lines := strings.Split(strings.TrimSpace(string(deadLetters)), "\n")
+	if len(lines) != 2 || !strings.Contains(string(deadLetters), `"id":"poison"`) || !strings.Contains(string(deadLetters), `"id":"rejected"`) {
+		t.Errorf("unexpected dead letter content %s", string(deadLetters))
+	}
+}
+
+func TestOutbox_Recover(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "outbox")
+	defer os.RemoveAll(dir)
+
+	o := newTestOutbox(t, dir)
+	if err := o.Put("left-over", []byte(`{}`)); err != nil {
+		t.Fatalf("put failed: %s", err.Error())
+	}
+	if pendingEntries(dir) != 1 {
+		t.Fatal("entry should be persisted before start")
+	}
+
+	delivered := make(chan string, 1)
+	restarted := newTestOutbox(t, dir)
+	if err := restarted.Start(func(payload []byte) error {
+		delivered <- string(payload)
+		return nil
+	}); err != nil {
+		t.Fatalf("outbox start failed: %s", err.Error())
+	}
+	defer restarted.Stop()
+
+	select {
+	case payload := <-delivered:
+		if payload != `{}` {
+			t.Errorf("unexpected payload %s", payload)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("left over entry was not delivered")
+	}
+	waitFor(t, func() bool { return pendingEntries(dir) == 0 })
+}
+
+func TestOutbox_Put(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "outbox")
+	defer os.RemoveAll(dir)
+
+	o := newTestOutbox(t, dir)
+	if err := o.Put("../escape", []byte(`{}`)); err == nil {
+		t.Error("ids containing path separators should be rejected")
+	}
+}
+
+func TestOutbox_PutPending(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "outbox")
+	defer os.RemoveAll(dir)
+
+	var lock sync.Mutex
+	deliveries := 0
+	release := make(chan struct{})
+
+	o := newTestOutbox(t, dir)
+	if err := o.Start(func(payload []byte) error {
+		lock.Lock()
+		deliveries++
+		lock.Unlock()
+		<-release
+		return nil
+	}); err != nil {
+		t.Fatalf("outbox start failed: %s", err.Error())
+	}
+	defer o.Stop()
+
+	for i := 0; i < 3; i++ {
+		if err := o.Put("redelivered", []byte(`{}`)); err != nil {
+			t.Fatalf("put failed: %s", err.Error())
+		}
+	}
+	close(release)
+	waitFor(t, func() bool { return pendingEntries(dir) == 0 })
+
+	lock.Lock()
+	defer lock.Unlock()
+	if deliveries != 1 {
+		t.Errorf("pending entry should be delivered once, got %d deliveries", deliveries)
+	}
+}
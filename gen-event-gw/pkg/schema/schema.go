@@ -0,0 +1,142 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+//defaultSchema - key of the schema applied to event types without an own schema
+const defaultSchema = "*"
+
+//rootPath - path of violations of the document itself
+const rootPath = "(root)"
+
+//Violation - a single part of the data that does not match the schema
+type Violation struct {
+	Path    string `json:"path"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+//ValidationError - the data of an event does not match the schema of its event type
+type ValidationError struct {
+	EventType  string
+	Violations []Violation
+}
+
+func (v *ValidationError) Error() string {
+	paths := make([]string, 0, len(v.Violations))
+	for _, violation := range v.Violations {
+		paths = append(paths, violation.Path)
+	}
+	return fmt.Sprintf("data does not match the schema of event type %q: %s", v.EventType, strings.Join(paths, ", "))
+}
+
+//Validator - validates event data against the schema of the event type
+type Validator struct {
+	schemas map[string]*gojsonschema.Schema
+}
+
+//New - reads the schema config, a map of event types to schema files relative to the config
+func New(file string) (*Validator, error) {
+	configData, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading schema config: %s", err.Error())
+	}
+
+	var schemaFiles map[string]string
+	if err := json.Unmarshal(configData, &schemaFiles); err != nil {
+		return nil, fmt.Errorf("error in schema config json: %s", err.Error())
+	}
+
+	configDir, err := filepath.Abs(filepath.Dir(file))
+	if err != nil {
+		return nil, fmt.Errorf("error resolving schema config directory: %s", err.Error())
+	}
+
+	schemas := make(map[string]*gojsonschema.Schema, len(schemaFiles))
+	for eventType, schemaFile := range schemaFiles {
+		if !filepath.IsAbs(schemaFile) {
+			schemaFile = filepath.Join(configDir, schemaFile)
+		}
+
+		//references inside the schema are resolved relative to the schema file
+		schema, err := gojsonschema.NewSchema(gojsonschema.NewReferenceLoader("file://" + filepath.ToSlash(schemaFile)))
+		if err != nil {
+			return nil, fmt.Errorf("error loading schema of event type %q: %s", eventType, err.Error())
+		}
+		schemas[eventType] = schema
+	}
+
+	return &Validator{schemas: schemas}, nil
+}
+
+//EventTypes - the event types with an own schema
+func (v *Validator) EventTypes() []string {
+	if v == nil {
+		return nil
+	}
+
+	eventTypes := make([]string, 0, len(v.schemas))
+	for eventType := range v.schemas {
+		if eventType != defaultSchema {
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+	return eventTypes
+}
+
+//Validate - returns a ValidationError if the data does not match, data of event types without schema is valid
+func (v *Validator) Validate(eventType string, data []byte) error {
+	if v == nil {
+		return nil
+	}
+
+	schema, ok := v.schemas[eventType]
+	if !ok {
+		schema, ok = v.schemas[defaultSchema]
+	}
+	if !ok {
+		return nil
+	}
+
+	result, err := schema.Validate(gojsonschema.NewBytesLoader(data))
+	if err != nil {
+		return fmt.Errorf("error validating event type %q: %s", eventType, err.Error())
+	}
+	if result.Valid() {
+		return nil
+	}
+
+	violations := make([]Violation, 0, len(result.Errors()))
+	for _, resultError := range result.Errors() {
+		violations = append(violations, Violation{
+			Path:    violationPath(resultError),
+			Rule:    resultError.Type(),
+			Message: resultError.Description(),
+		})
+	}
+	return &ValidationError{EventType: eventType, Violations: violations}
+}
+
+//violationPath - missing properties are reported on the path of the property instead of the parent
+func violationPath(resultError gojsonschema.ResultError) string {
+	path := resultError.Field()
+	if resultError.Type() != "required" {
+		return path
+	}
+
+	property, ok := resultError.Details()["property"].(string)
+	if !ok {
+		return path
+	}
+	if path == rootPath {
+		return property
+	}
+	return path + "." + property
+}
@@ -0,0 +1,96 @@
+package schema
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+
+	_, err := New("testdata/schema_valid.json")
+
+	if err != nil {
+		t.Errorf("Reading valid config failed: %s", err.Error())
+	}
+
+	_, err = New("testdata/schema_invalid.json")
+
+	if err == nil {
+		t.Errorf("Reading config with missing schema should fail")
+	}
+}
+
+func TestValidate(t *testing.T) {
+
+	validator, err := New("testdata/schema_valid.json")
+	if err != nil {
+		t.Fatalf("Reading valid config failed: %s", err.Error())
+	}
+
+	tests := []struct {
+		name       string
+		eventType  string
+		data       string
+		violations map[string]string
+	}{
+		{
+			"valid",
+			"order.created",
+			`{"order":{"id":"A-1","status":"new","customer":{"email":"jane@example.com"}}}`,
+			nil,
+		},
+		{
+			"violations",
+			"order.created",
+			`{"order":{"id":1,"status":"lost","customer":{"email":"jane"}}}`,
+			map[string]string{"order.id": "invalid_type", "order.status": "enum", "order.customer.email": "format"},
+		},
+		{
+			"missing fields",
+			"order.created",
+			`{"order":{"status":"new"}}`,
+			map[string]string{"order.id": "required"},
+		},
+		{
+			"missing root field",
+			"order.created",
+			`{}`,
+			map[string]string{"order": "required"},
+		},
+		{
+			"event type without schema",
+			"order.deleted",
+			`"anything"`,
+			nil,
+		},
+	}
+
+	for _, test := range tests {
+		err := validator.Validate(test.eventType, []byte(test.data))
+		if test.violations == nil {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %s", test.name, err.Error())
+			}
+			continue
+		}
+
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("%s: expected validation error, got %v", test.name, err)
+			continue
+		}
+		if len(validationErr.Violations) != len(test.violations) {
+			t.Errorf("%s: expected %d violations, got %+v", test.name, len(test.violations), validationErr.Violations)
+		}
+		for _, violation := range validationErr.Violations {
+			if test.violations[violation.Path] != violation.Rule {
+				t.Errorf("%s: unexpected violation %+v", test.name, violation)
+			}
+		}
+	}
+
+	var nilValidator *Validator
+	if err := nilValidator.Validate("order.created", []byte(`{}`)); err != nil {
+		t.Errorf("nil validator should accept all data")
+	}
+}
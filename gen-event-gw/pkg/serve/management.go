@@ -0,0 +1,214 @@
+package serve
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/config"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const dialTimeout = 2 * time.Second
+
+var (
+	errShuttingDown = errors.New("shutting down")
+	errNotChecked   = errors.New("event buses have not been checked yet")
+	errUnreachable  = errors.New("no event bus is reachable")
+)
+
+//newManagementServer - health checks and metrics, served on a separate port
+func newManagementServer(cfg *config.Config, ready http.HandlerFunc) *http.Server {
+	serveMux := http.NewServeMux()
+	serveMux.HandleFunc("/healthz", healthz)
+	serveMux.HandleFunc("/ready", ready)
+	serveMux.Handle("/metrics", promhttp.Handler())
+
+	return &http.Server{
+		Addr:    fmt.Sprintf(":%d", cfg.ManagementPort),
+		Handler: serveMux,
+	}
+}
+
+//newReadyHandler - the event buses are checked in the background every ready check interval,
+//the probes get the result of the last check, so a slow event bus does not delay them
+func newReadyHandler(cfg *config.Config, state *readiness) (http.HandlerFunc, error) {
+	apps := make([]appEventBuses, 0, len(cfg.Apps))
+	for _, app := range cfg.Apps {
+		buses := appEventBuses{app: app.Name}
+		for _, rawURL := range targetURLs(&app) {
+			publishURL, err := url.Parse(rawURL)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid configuration - event publish url %q of app %s: %s", rawURL, app.Name, err.Error())
+			}
+			buses.publishURLs = append(buses.publishURLs, publishURL)
+		}
+		apps = append(apps, buses)
+	}
+
+	if cfg.ReadyCheckInterval <= 0 {
+		return ready(state, nil), nil
+	}
+
+	checker := &reachability{apps: apps, err: errNotChecked}
+	go checker.run(cfg.ReadyCheckInterval)
+	return ready(state, checker), nil
+}
+
+//targetURLs - urls of the targets of the app, the event publish url if no targets are configured
+func targetURLs(app *config.AppConfig) []string {
+	if len(app.Targets) == 0 {
+		return []string{app.EventPublishURL}
+	}
+
+	var urls []string
+	for _, target := range app.Targets {
+		if target.Type != config.TargetFile {
+			urls = append(urls, target.URL)
+		}
+	}
+	return urls
+}
+
+//healthz - the process is alive as long as it answers
+func healthz(w http.ResponseWriter, r *http.Request) {
+	writeStatus(w, http.StatusOK, nil)
+}
+
+//readiness - flipped once the shutdown started, so no new events are routed to the instance
+type readiness struct {
+	shuttingDown int32
+}
+
+func (r *readiness) shutdown() {
+	atomic.StoreInt32(&r.shuttingDown, 1)
+}
+
+func (r *readiness) isShuttingDown() bool {
+	return atomic.LoadInt32(&r.shuttingDown) == 1
+}
+
+//ready - forwarding does not make a lot of sense if no event bus is reachable. Unreachable event buses
+//are reported by app, but as long as one is reachable the other apps keep receiving events
+func ready(state *readiness, checker *reachability) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if state.isShuttingDown() {
+			writeStatus(w, http.StatusServiceUnavailable, errShuttingDown)
+			return
+		}
+
+		status := http.StatusOK
+		var err error
+		var unreachable map[string]map[string]string
+		if checker != nil {
+			unreachable, err = checker.result()
+			if err != nil {
+				status = http.StatusServiceUnavailable
+			}
+		}
+
+		body := statusBody(err)
+		if len(unreachable) > 0 {
+			body["unreachable"] = unreachable
+		}
+		writeJSON(w, status, body)
+	}
+}
+
+//appEventBuses - the event buses an app forwards to
+type appEventBuses struct {
+	app         string
+	publishURLs []*url.URL
+}
+
+//reachability - result of the last reachability check of the event buses
+type reachability struct {
+	apps []appEventBuses
+
+	lock sync.RWMutex
+	err  error
+	//unreachable - errors of the unreachable event buses by app and url
+	unreachable map[string]map[string]string
+}
+
+func (c *reachability) run(interval time.Duration) {
+	for {
+		c.check()
+		time.Sleep(interval)
+	}
+}
+
+func (c *reachability) check() {
+	unreachable := map[string]map[string]string{}
+	checked, reached := 0, 0
+	for _, buses := range c.apps {
+		for _, publishURL := range buses.publishURLs {
+			checked++
+			if err := checkReachable(publishURL); err != nil {
+				if unreachable[buses.app] == nil {
+					unreachable[buses.app] = map[string]string{}
+				}
+				unreachable[buses.app][publishURL.String()] = err.Error()
+				continue
+			}
+			reached++
+		}
+	}
+
+	var err error
+	if checked > 0 && reached == 0 {
+		err = errUnreachable
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.err = err
+	c.unreachable = unreachable
+}
+
+func (c *reachability) result() (map[string]map[string]string, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.unreachable, c.err
+}
+
+func checkReachable(target *url.URL) error {
+	port := target.Port()
+	if port == "" {
+		port = "80"
+		if strings.EqualFold(target.Scheme, "https") {
+			port = "443"
+		}
+	}
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(target.Hostname(), port), dialTimeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func writeStatus(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, statusBody(err))
+}
+
+func statusBody(err error) map[string]interface{} {
+	if err != nil {
+		return map[string]interface{}{"code": "error", "error": err.Error()}
+	}
+	return map[string]interface{}{"code": "success"}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
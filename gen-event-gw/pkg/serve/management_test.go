@@ -0,0 +1,82 @@
+package serve
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func closedURL(t *testing.T) *url.URL {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening failed: %s", err.Error())
+	}
+	closed, _ := url.Parse("http://" + listener.Addr().String())
+	listener.Close()
+	return closed
+}
+
+func TestReady_Reachability(t *testing.T) {
+
+	bus := httptest.NewServer(http.NotFoundHandler())
+	defer bus.Close()
+	reachable, _ := url.Parse(bus.URL)
+	unreachable := closedURL(t)
+
+	tests := []struct {
+		name        string
+		apps        []appEventBuses
+		status      int
+		unreachable map[string]int
+	}{
+		{"all reachable", []appEventBuses{{app: "shop", publishURLs: []*url.URL{reachable}}}, http.StatusOK, nil},
+		{"one app unreachable", []appEventBuses{
+			{app: "shop", publishURLs: []*url.URL{reachable}},
+			{app: "crm", publishURLs: []*url.URL{unreachable}},
+		}, http.StatusOK, map[string]int{"crm": 1}},
+		{"none reachable", []appEventBuses{
+			{app: "shop", publishURLs: []*url.URL{unreachable}},
+			{app: "crm", publishURLs: []*url.URL{unreachable}},
+		}, http.StatusServiceUnavailable, map[string]int{"shop": 1, "crm": 1}},
+		{"file targets only", []appEventBuses{{app: "audit"}}, http.StatusOK, nil},
+	}
+
+	for _, test := range tests {
+		checker := &reachability{apps: test.apps, err: errNotChecked}
+		checker.check()
+
+		recorder := httptest.NewRecorder()
+		ready(&readiness{}, checker)(recorder, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+		if recorder.Code != test.status {
+			t.Errorf("%s: expected status %d, got %d", test.name, test.status, recorder.Code)
+		}
+		var body struct {
+			Unreachable map[string]map[string]string `json:"unreachable"`
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: invalid body %s", test.name, recorder.Body.String())
+		}
+		if len(body.Unreachable) != len(test.unreachable) {
+			t.Errorf("%s: expected unreachable %v, got %v", test.name, test.unreachable, body.Unreachable)
+		}
+		for app, count := range test.unreachable {
+			if len(body.Unreachable[app]) != count {
+				t.Errorf("%s: expected %d unreachable event buses of %s, got %v", test.name, count, app, body.Unreachable)
+			}
+		}
+	}
+}
+
+func TestReady_NotChecked(t *testing.T) {
+
+	recorder := httptest.NewRecorder()
+	ready(&readiness{}, &reachability{err: errNotChecked})(recorder, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503 before the first check, got %d", recorder.Code)
+	}
+}
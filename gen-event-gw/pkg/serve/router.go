@@ -1,11 +1,23 @@
 package serve
 
 import (
-	"crypto/subtle"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/auth"
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/capture"
 	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/config"
 	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/events"
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/limit"
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/metrics"
 
 	log "github.com/sirupsen/logrus"
 
@@ -13,60 +25,216 @@ import (
 )
 
 //NewRouter - sets up routing
-func NewRouter() error {
+func NewRouter(cfg *config.Config) error {
 	log.Printf("Starting server with config...")
-	log.Printf("AppName:%s, EventPublishURL:%s, UserName:%s", *config.GlobalConfig.AppName, *config.GlobalConfig.EventPublishURL, *config.GlobalConfig.UserName)
 
 	router := mux.NewRouter().StrictSlash(true)
 
-	t := events.NewKymaEventProcesser()
+	o, err := events.NewOutbox(cfg.Outbox)
+	if err != nil {
+		return err
+	}
+
+	archive, err := newArchive(cfg.Capture)
+	if err != nil {
+		return err
+	}
+
+	var global *limit.Limiter
+	if cfg.Limits.Rate > 0 {
+		log.Printf("Global rate limit: %g requests per second, bursts of %d", cfg.Limits.Rate, cfg.Limits.Burst)
+		global = limit.NewLimiter(metrics.LimitGlobal, cfg.Limits.Rate, cfg.Limits.Burst)
+	}
+
+	state := &readiness{}
+	readyHandler, err := newReadyHandler(cfg, state)
+	if err != nil {
+		return err
+	}
+
+	//deprecated, the probes are served on the events port until they have moved to the management port
+	router.HandleFunc("/healthz", healthz)
+	router.HandleFunc("/ready", readyHandler)
 
-	eventsHandler := http.HandlerFunc(t.EventsHandler)
+	processers := make(map[string]*events.KymaEventProcesser, len(cfg.Apps))
+	for i := range cfg.Apps {
+		app := &cfg.Apps[i]
+		log.Printf("AppName:%s, EventPublishURL:%s, EventFormat:%s", app.Name, app.EventPublishURL, app.EventFormat)
 
-	router.Handle("/events", authMiddleware(eventsHandler)).Methods("POST")
+		if app.Auth.Mode == auth.ModeMTLS && (cfg.TLS.CertFile == "" || cfg.TLS.ClientCAFile == "") {
+			return fmt.Errorf("Invalid configuration - mtls authentication of app %s requires a server certificate and a client CA", app.Name)
+		}
 
-	router.HandleFunc("/healthz", healthz)
-	router.HandleFunc("/ready", ready)
+		t, err := events.NewKymaEventProcesser(app, o, archive)
+		if err != nil {
+			return err
+		}
+		processers[app.Name] = t
 
-	return http.ListenAndServe(":8080", router)
+		authenticator, err := newAuthenticator(app)
+		if err != nil {
+			return err
+		}
 
-}
+		protect := newProtection(cfg, app, global, authenticator)
+		eventsHandler := metrics.Middleware(protect(http.HandlerFunc(t.EventsHandler)))
+		batchHandler := metrics.Middleware(withDeadline(cfg.WriteTimeout, protect(http.HandlerFunc(t.BatchHandler))))
 
-// middleware concept, as explained e.g. here: https://www.alexedwards.net/blog/making-and-using-middleware
-func authMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		passwordConfig := *config.GlobalConfig.Password
-		usernameConfig := *config.GlobalConfig.UserName
+		router.Handle(fmt.Sprintf("/apps/%s/events", app.Name), eventsHandler).Methods("POST")
+		router.Handle(fmt.Sprintf("/apps/%s/events/batch", app.Name), batchHandler).Methods("POST")
+		//a single app is still served on the original path
+		if len(cfg.Apps) == 1 {
+			router.Handle("/events", eventsHandler).Methods("POST")
+			router.Handle("/events/batch", batchHandler).Methods("POST")
+		}
+	}
 
-		if passwordConfig == "" || usernameConfig == "" {
-			log.Println("Basic Authentication has not been configured")
-			next.ServeHTTP(w, r)
+	if o != nil {
+		if err := events.StartDelivery(o, processers); err != nil {
+			return err
+		}
+	}
+
+	management := newManagementServer(cfg, readyHandler)
+
+	server := &http.Server{
+		Addr:         fmt.Sprintf(":%d", cfg.Port),
+		Handler:      router,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+
+	if cfg.TLS.CertFile != "" {
+		server.TLSConfig, err = newTLSConfig(cfg)
+		if err != nil {
+			return err
+		}
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+
+	errs := make(chan error, 2)
+	go func() {
+		errs <- management.ListenAndServe()
+	}()
+	go func() {
+		if cfg.TLS.CertFile == "" {
+			errs <- server.ListenAndServe()
 			return
 		}
+		errs <- server.ListenAndServeTLS(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+	}()
 
-		user, pass, ok := r.BasicAuth()
+	select {
+	case err := <-errs:
+		return err
+	case sig := <-signals:
+		log.Printf("Received %s, shutting down", sig)
+	}
 
-		if !ok || subtle.ConstantTimeCompare([]byte(user), []byte(usernameConfig)) != 1 || subtle.ConstantTimeCompare([]byte(pass), []byte(passwordConfig)) != 1 {
-			log.Println("Unauthorized request has been attempted")
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
+	return shutdown(cfg.ShutdownTimeout, state, server, management, o, archive, processers)
+}
 
-		next.ServeHTTP(w, r)
+//newArchive - capture archive if a capture directory is configured, nil otherwise
+func newArchive(captureConfig config.CaptureConfig) (*capture.Archive, error) {
+	if captureConfig.Dir == "" {
+		return nil, nil
+	}
+
+	log.Printf("Capturing received requests in %s", captureConfig.Dir)
+	return capture.New(capture.Config{
+		Dir:         captureConfig.Dir,
+		MaxFileSize: captureConfig.MaxFileSize,
+		MaxFiles:    captureConfig.MaxFiles,
 	})
 }
 
-//TODO
-func healthz(w http.ResponseWriter, r *http.Request) {
-
-	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte(`{"code": success}`))
-
+//newProtection - wraps the handlers of the app with the body size limit, the rate limits and authentication
+//Requests are limited per ip before and per principal after authentication, so rejected requests do not reach the authenticator.
+//All handlers of the app share the same rate limits.
+func newProtection(cfg *config.Config, app *config.AppConfig, global *limit.Limiter, authenticator auth.Authenticator) func(http.Handler) http.Handler {
+	var source *limit.Limiter
+	if app.Limits.Rate > 0 {
+		log.Printf("Rate limit of app %s: %g requests per second and %s, bursts of %d", app.Name, app.Limits.Rate, app.Limits.Key, app.Limits.Burst)
+		source = limit.NewLimiter(metrics.LimitSource, app.Limits.Rate, app.Limits.Burst)
+	}
+
+	maxBodySize := cfg.Limits.MaxBodySize
+	if app.Limits.MaxBodySize > 0 {
+		maxBodySize = app.Limits.MaxBodySize
+	}
+
+	return func(handler http.Handler) http.Handler {
+		if source != nil && app.Limits.Key == limit.KeyPrincipal {
+			handler = limit.Middleware(source, limit.Principal(cfg.Limits.ClientIPHeader), handler)
+		}
+		handler = auth.Middleware(authenticator, handler)
+		if source != nil && app.Limits.Key == limit.KeyIP {
+			handler = limit.Middleware(source, limit.ClientIP(cfg.Limits.ClientIPHeader), handler)
+		}
+		if global != nil {
+			handler = limit.Middleware(global, limit.Global, handler)
+		}
+		return limit.BodySize(maxBodySize, handler)
+	}
 }
 
-func ready(w http.ResponseWriter, r *http.Request) {
+//withDeadline - batches have to be answered before the write timeout closes the connection, a tenth of the write
+//timeout is left for writing the per event results
+func withDeadline(writeTimeout time.Duration, next http.Handler) http.Handler {
+	if writeTimeout <= 0 {
+		return next
+	}
+	deadline := writeTimeout - writeTimeout/10
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), deadline)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte(`{"code": success}`))
+//newAuthenticator - creates the authenticator of the auth mode configured for the app
+func newAuthenticator(app *config.AppConfig) (auth.Authenticator, error) {
+	if app.Auth.Mode == auth.ModeNone {
+		log.Printf("Authentication is disabled for app %s with auth mode none, all requests will be accepted", app.Name)
+	} else {
+		log.Printf("Authentication mode of app %s: %s", app.Name, app.Auth.Mode)
+	}
+
+	authenticator, err := auth.New(app.Auth)
+	if err != nil {
+		return nil, fmt.Errorf("app %s: %s", app.Name, err.Error())
+	}
+	return authenticator, nil
+}
 
+//newTLSConfig - client certificates are verified against the client CA, they are required in mtls mode
+func newTLSConfig(cfg *config.Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	caFile := cfg.TLS.ClientCAFile
+	if caFile == "" {
+		return tlsConfig, nil
+	}
+
+	caData, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading client CA: %s", err.Error())
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caData) {
+		return nil, fmt.Errorf("client CA %s does not contain any PEM encoded certificate", caFile)
+	}
+
+	tlsConfig.ClientCAs = pool
+	tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	//a single mtls app does not need to accept anonymous clients
+	if len(cfg.Apps) == 1 && cfg.Apps[0].Auth.Mode == auth.ModeMTLS {
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return tlsConfig, nil
 }
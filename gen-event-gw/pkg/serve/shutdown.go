@@ -0,0 +1,80 @@
+package serve
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/capture"
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/events"
+	"github.com/kyma-incubator/connector-tools/gen-event-gw/pkg/outbox"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//shutdown - stops accepting events and waits for the in-flight events, at most until the timeout
+//Readiness fails from the start, health checks and metrics are served until the end
+func shutdown(timeout time.Duration, state *readiness, server *http.Server, management *http.Server, o *outbox.Outbox, archive *capture.Archive, processers map[string]*events.KymaEventProcesser) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	state.shutdown()
+
+	var result error
+
+	//waits for the running requests, including the events that are forwarded synchronously
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Requests did not finish in time - %s", err.Error())
+		result = err
+	}
+
+	if o != nil {
+		if err := stopOutbox(ctx, o); err != nil {
+			log.Printf("Outbox deliveries did not finish in time - %s", err.Error())
+			result = err
+		}
+	}
+
+	for name, k := range processers {
+		if err := k.Drain(ctx); err != nil {
+			log.Printf("Forwarding events of app %s did not finish in time - %s", name, err.Error())
+			result = err
+		}
+		if err := k.Close(); err != nil {
+			log.Printf("Closing app %s failed - %s", name, err.Error())
+		}
+	}
+
+	if archive != nil {
+		if err := archive.Close(); err != nil {
+			log.Printf("Closing capture archive failed - %s", err.Error())
+		}
+	}
+
+	if err := management.Close(); err != nil {
+		log.Printf("Closing management server failed - %s", err.Error())
+	}
+
+	if result != nil {
+		return fmt.Errorf("shutdown did not complete within %s", timeout)
+	}
+	log.Println("Shutdown completed")
+	return nil
+}
+
+//stopOutbox - pending events stay in the outbox and are forwarded after the restart
+func stopOutbox(ctx context.Context, o *outbox.Outbox) error {
+	done := make(chan struct{})
+	go func() {
+		o.Stop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,265 @@
+package transform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"text/template"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+//defaultPipeline - key of the pipeline applied to event types without an own pipeline
+const defaultPipeline = "*"
+
+//pipelineConfig - transformations of a single event type, applied in the order of the fields
+type pipelineConfig struct {
+	Project  fieldList `json:"project"`
+	Rename   fieldList `json:"rename"`
+	Remove   []string  `json:"remove"`
+	Inject   fieldList `json:"inject"`
+	Template string    `json:"template"`
+}
+
+//field - entry of a json object in the config, the value is kept as raw json
+type field struct {
+	key   string
+	value gjson.Result
+}
+
+//fieldList - json object of the config that keeps the declared order of its fields
+type fieldList []field
+
+func (f *fieldList) UnmarshalJSON(data []byte) error {
+	result := gjson.ParseBytes(data)
+	if !result.IsObject() {
+		return fmt.Errorf("expected a json object, got %s", result.Raw)
+	}
+
+	result.ForEach(func(key, value gjson.Result) bool {
+		*f = append(*f, field{key: key.String(), value: value})
+		return true
+	})
+	return nil
+}
+
+type pipeline struct {
+	project  fieldList
+	rename   fieldList
+	remove   []string
+	inject   fieldList
+	template *template.Template
+}
+
+//Transformer - applies the configured pipeline of the event type to the event data
+type Transformer struct {
+	pipelines map[string]*pipeline
+}
+
+//New - reads the transformation config and compiles the contained templates
+func New(file string) (*Transformer, error) {
+	configData, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading transformation config: %s", err.Error())
+	}
+
+	var pipelineConfigs map[string]pipelineConfig
+	if err := json.Unmarshal(configData, &pipelineConfigs); err != nil {
+		return nil, fmt.Errorf("error in transformation config json: %s", err.Error())
+	}
+
+	pipelines := make(map[string]*pipeline, len(pipelineConfigs))
+	for eventType, currentConfig := range pipelineConfigs {
+		p := &pipeline{
+			project: currentConfig.Project,
+			rename:  currentConfig.Rename,
+			remove:  currentConfig.Remove,
+			inject:  currentConfig.Inject,
+		}
+
+		for _, f := range append(p.project, p.rename...) {
+			if f.value.Type != gjson.String {
+				return nil, fmt.Errorf("path of field %q of event type %q must be a string", f.key, eventType)
+			}
+		}
+
+		if currentConfig.Template != "" {
+			p.template, err = template.New(eventType).Funcs(templateFuncs).Option("missingkey=zero").Parse(currentConfig.Template)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing template of event type %q: %s", eventType, err.Error())
+			}
+		}
+
+		pipelines[eventType] = p
+	}
+
+	return &Transformer{pipelines: pipelines}, nil
+}
+
+//Apply - transforms the data, data of event types without pipeline is returned unchanged
+func (t *Transformer) Apply(eventType string, data []byte) ([]byte, error) {
+	if t == nil {
+		return data, nil
+	}
+
+	p, ok := t.pipelines[eventType]
+	if !ok {
+		p, ok = t.pipelines[defaultPipeline]
+	}
+	if !ok {
+		return data, nil
+	}
+
+	result, err := p.apply(data)
+	if err != nil {
+		return nil, fmt.Errorf("error transforming event type %q: %s", eventType, err.Error())
+	}
+	return result, nil
+}
+
+//apply - edits the raw json, so the order and formatting of untouched fields is kept.
+//If no step matches the data is returned unchanged
+func (p *pipeline) apply(data []byte) ([]byte, error) {
+	if !gjson.ValidBytes(data) {
+		return nil, fmt.Errorf("data is not valid json")
+	}
+
+	var err error
+	result := data
+
+	if p.project != nil {
+		result = []byte("{}")
+		for _, f := range p.project {
+			value := gjson.GetBytes(data, f.value.String())
+			if !value.Exists() {
+				continue
+			}
+			if result, err = setRaw(result, f.key, []byte(value.Raw)); err != nil {
+				return nil, fmt.Errorf("error projecting field %q: %s", f.key, err.Error())
+			}
+		}
+	}
+
+	if len(p.rename) > 0 || len(p.remove) > 0 || len(p.inject) > 0 {
+		if !gjson.ParseBytes(result).IsObject() {
+			return nil, fmt.Errorf("rename, remove and inject require a json object")
+		}
+
+		for _, f := range p.rename {
+			value := gjson.GetBytes(result, f.key)
+			if !value.Exists() {
+				continue
+			}
+			if result, err = sjson.DeleteBytes(result, f.key); err != nil {
+				return nil, fmt.Errorf("error renaming field %q: %s", f.key, err.Error())
+			}
+			if result, err = setRaw(result, f.value.String(), []byte(value.Raw)); err != nil {
+				return nil, fmt.Errorf("error renaming field %q: %s", f.key, err.Error())
+			}
+		}
+		for _, path := range p.remove {
+			if !gjson.GetBytes(result, path).Exists() {
+				continue
+			}
+			if result, err = sjson.DeleteBytes(result, path); err != nil {
+				return nil, fmt.Errorf("error removing field %q: %s", path, err.Error())
+			}
+		}
+		for _, f := range p.inject {
+			if result, err = setRaw(result, f.key, []byte(f.value.Raw)); err != nil {
+				return nil, fmt.Errorf("error injecting field %q: %s", f.key, err.Error())
+			}
+		}
+	}
+
+	if p.template != nil {
+		value, err := decode(result)
+		if err != nil {
+			return nil, fmt.Errorf("data is not valid json: %s", err.Error())
+		}
+
+		var buf bytes.Buffer
+		if err := p.template.Execute(&buf, value); err != nil {
+			return nil, fmt.Errorf("error executing template: %s", err.Error())
+		}
+		if !json.Valid(buf.Bytes()) {
+			return nil, fmt.Errorf("template did not produce valid json: %s", buf.String())
+		}
+		return buf.Bytes(), nil
+	}
+
+	return result, nil
+}
+
+var templateFuncs = template.FuncMap{
+	//json - renders a value as json, e.g. {"id": {{json .order.id}}}
+	"json": func(value interface{}) (string, error) {
+		result, err := json.Marshal(value)
+		return string(result), err
+	},
+}
+
+//decode - numbers are kept as json.Number so they are not altered by float conversion
+func decode(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+//setRaw - sets the raw json at a dot separated path. New fields are appended to their object, so the
+//existing fields keep their order, missing objects on the way are created
+func setRaw(data []byte, path string, raw []byte) ([]byte, error) {
+	if gjson.GetBytes(data, path).Exists() {
+		return sjson.SetRawBytes(data, path, raw)
+	}
+
+	parent := gjson.ParseBytes(data)
+	end := bytes.LastIndexByte(data, '}')
+	key := path
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		parentPath := path[:i]
+		key = path[i+1:]
+
+		if !gjson.GetBytes(data, parentPath).IsObject() {
+			var err error
+			if data, err = setRaw(data, parentPath, []byte("{}")); err != nil {
+				return nil, err
+			}
+		}
+		parent = gjson.GetBytes(data, parentPath)
+		end = parent.Index + len(parent.Raw) - 1
+	}
+	if !parent.IsObject() || end < 0 {
+		return nil, fmt.Errorf("%q is not inside a json object", path)
+	}
+
+	empty := true
+	parent.ForEach(func(_, _ gjson.Result) bool {
+		empty = false
+		return false
+	})
+
+	//insert after the last field, before any whitespace preceding the closing brace
+	for end > 0 && data[end-1] <= ' ' {
+		end--
+	}
+	encodedKey, _ := json.Marshal(key)
+
+	result := make([]byte, 0, len(data)+len(encodedKey)+len(raw)+2)
+	result = append(result, data[:end]...)
+	if !empty {
+		result = append(result, ',')
+	}
+	result = append(result, encodedKey...)
+	result = append(result, ':')
+	result = append(result, raw...)
+	return append(result, data[end:]...), nil
+}
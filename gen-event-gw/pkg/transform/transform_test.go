@@ -0,0 +1,97 @@
+package transform
+
+import "testing"
+
+func TestNew(t *testing.T) {
+
+	_, err := New("testdata/transform_valid.json")
+
+	if err != nil {
+		t.Errorf("Reading valid config failed: %s", err.Error())
+	}
+
+	_, err = New("testdata/transform_invalid.json")
+
+	if err == nil {
+		t.Errorf("Reading invalid config should fail")
+	}
+}
+
+func TestApply(t *testing.T) {
+
+	transformer, err := New("testdata/transform_valid.json")
+	if err != nil {
+		t.Fatalf("Reading valid config failed: %s", err.Error())
+	}
+
+	tests := []struct {
+		name      string
+		eventType string
+		data      string
+		expected  string
+	}{
+		{
+			"pipeline",
+			"order.created",
+			`{"order":{"id":12345678901234567890,"status":"new","customer":{"name":"Jane","email":"jane@example.com"},"items":[1,2]}}`,
+			`{"id":12345678901234567890,"customer":{"name":"Jane"},"state":"new","source":"shop","meta":{"version":2}}`,
+		},
+		{
+			"rename in declared order",
+			"order.updated",
+			`{"a":1,"b":2,"x":{"y":3,"w":4}}`,
+			`{"x":{"w":4,"z":{"y":3}},"c":1}`,
+		},
+		{
+			"template",
+			"order.deleted",
+			`{"order":{"id":"A-1"},"reason":"cancelled"}`,
+			`{"orderId": "A-1", "reason": "cancelled"}`,
+		},
+		{
+			"default pipeline",
+			"user.created",
+			`{"user":"jane","password":"secret","age":30}`,
+			`{"user":"jane","age":30}`,
+		},
+		{
+			"keeps field order",
+			"user.created",
+			`{"z":1,"b":{"y":2,"a":3},"password":"secret","a":4}`,
+			`{"z":1,"b":{"y":2,"a":3},"a":4}`,
+		},
+		{
+			"no matching step",
+			"user.created",
+			"{ \"z\": 1.50,\n  \"a\": [ 2 ] }",
+			"{ \"z\": 1.50,\n  \"a\": [ 2 ] }",
+		},
+	}
+
+	for _, test := range tests {
+		result, err := transformer.Apply(test.eventType, []byte(test.data))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.name, err.Error())
+			continue
+		}
+		if string(result) != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.name, test.expected, string(result))
+		}
+	}
+
+	_, err = transformer.Apply("order.created", []byte(`[1,2]`))
+	if err != nil {
+		t.Errorf("projection of arrays should be possible: %s", err.Error())
+	}
+
+	_, err = transformer.Apply("user.created", []byte(`[1,2]`))
+	if err == nil {
+		t.Error("remove on arrays should fail")
+	}
+
+	var nilTransformer *Transformer
+	result, err := nilTransformer.Apply("order.created", []byte(`{"a":1}`))
+	if err != nil || string(result) != `{"a":1}` {
+		t.Error("nil transformer should return the data unchanged")
+	}
+}
@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/config"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/logger"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/model/errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const signaturePrefix = "sha256="
+
+// Middleware rejects webhook calls from addresses outside the allowlist with 403
+// and calls without valid basic auth credentials or webhook secret with 401.
+// Without any of them all calls are rejected, unless authentication is skipped explicitly
+func Middleware(opts *config.Opts) func(http.Handler) http.Handler {
+	skipAuth := !opts.AuthConfigured() && opts.InsecureSkipAuth
+	if skipAuth {
+		logger.Logger.Warn("authentication is skipped, all webhook calls are accepted")
+	} else if opts.WebhookSecret == "" {
+		logger.Logger.Warn("webhook secret is not configured, signatures of webhook calls are not verified")
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !skipAuth && !opts.AuthConfigured() {
+				errors.HandleError(w, r, fmt.Errorf("authentication is not configured"), errors.UnAuthorized)
+				return
+			}
+
+			if err := checkAllowed(opts, r); err != nil {
+				errors.HandleError(w, r, err, errors.Forbidden)
+				return
+			}
+
+			if err := checkBasicAuth(opts, r); err != nil {
+				errors.HandleError(w, r, err, errors.UnAuthorized)
+				return
+			}
+
+			if err := checkSecret(opts, r); err != nil {
+				errors.HandleError(w, r, err, errors.UnAuthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func checkAllowed(opts *config.Opts, r *http.Request) error {
+	if len(opts.AllowedNetworks) == 0 {
+		return nil
+	}
+
+	address := clientIP(opts.ClientIPHeader, r)
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return fmt.Errorf("invalid client address %q", address)
+	}
+	for _, network := range opts.AllowedNetworks {
+		if network.Contains(ip) {
+			return nil
+		}
+	}
+	return fmt.Errorf("client address %s is not allowed", address)
+}
+
+// clientIP is the last address of the header as appended by the ingress, or the remote address
+func clientIP(header string, r *http.Request) string {
+	if header != "" {
+		if values := r.Header[http.CanonicalHeaderKey(header)]; len(values) > 0 {
+			addresses := strings.Split(values[len(values)-1], ",")
+			if address := strings.TrimSpace(addresses[len(addresses)-1]); address != "" {
+				return address
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func checkBasicAuth(opts *config.Opts, r *http.Request) error {
+	if opts.BasicAuthUser == "" {
+		return nil
+	}
+
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return fmt.Errorf("missing basic auth credentials")
+	}
+	if !equal(user, opts.BasicAuthUser) || !equal(password, opts.BasicAuthPassword) {
+		return fmt.Errorf("invalid basic auth credentials of user %q", user)
+	}
+	return nil
+}
+
+func checkSecret(opts *config.Opts, r *http.Request) error {
+	if opts.WebhookSecret == "" {
+		return nil
+	}
+
+	if opts.SignatureMode == config.SignatureModeQuery {
+		secret := r.URL.Query().Get(opts.SecretQueryParam)
+		if secret == "" {
+			return fmt.Errorf("missing webhook secret in query parameter %s", opts.SecretQueryParam)
+		}
+		if !equal(secret, opts.WebhookSecret) {
+			return fmt.Errorf("invalid webhook secret")
+		}
+		return nil
+	}
+
+	signature := strings.TrimPrefix(strings.TrimSpace(r.Header.Get(opts.SignatureHeader)), signaturePrefix)
+	if signature == "" {
+		return fmt.Errorf("missing webhook signature in header %s", opts.SignatureHeader)
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	if !validSignature(opts.WebhookSecret, body, signature) {
+		return fmt.Errorf("invalid webhook signature")
+	}
+	return nil
+}
+
+// validSignature accepts the HMAC-SHA256 of the body hex or base64 encoded
+func validSignature(secret string, body []byte, signature string) bool {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+
+	if decoded, err := hex.DecodeString(signature); err == nil && hmac.Equal(decoded, expected) {
+		return true
+	}
+	if decoded, err := base64.StdEncoding.DecodeString(signature); err == nil && hmac.Equal(decoded, expected) {
+		return true
+	}
+	return false
+}
+
+func equal(actual string, expected string) bool {
+	return subtle.ConstantTimeCompare([]byte(actual), []byte(expected)) == 1
+}
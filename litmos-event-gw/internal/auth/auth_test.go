@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/config"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/logger"
+	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const body = `{"id":4513,"type":"achievement.earned"}`
+
+func serve(opts *config.Opts, r *http.Request) (int, string) {
+	var received string
+	handler := Middleware(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		received = string(b)
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, r)
+	return recorder.Code, received
+}
+
+func sign(secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return mac.Sum(nil)
+}
+
+func TestSignatureHeader(t *testing.T) {
+	g := NewGomegaWithT(t)
+	logger.Initialize()
+
+	opts := &config.Opts{WebhookSecret: "secret", SignatureMode: config.SignatureModeHeader, SignatureHeader: "X-Litmos-Signature"}
+
+	for _, signature := range []string{hex.EncodeToString(sign("secret")), base64.StdEncoding.EncodeToString(sign("secret")), "sha256=" + hex.EncodeToString(sign("secret"))} {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("X-Litmos-Signature", signature)
+		status, received := serve(opts, req)
+		g.Expect(status).To(Equal(http.StatusOK))
+		g.Expect(received).To(Equal(body))
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("X-Litmos-Signature", hex.EncodeToString(sign("other")))
+	status, _ := serve(opts, req)
+	g.Expect(status).To(Equal(http.StatusUnauthorized))
+
+	status, _ = serve(opts, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	g.Expect(status).To(Equal(http.StatusUnauthorized))
+}
+
+func TestSecretQuery(t *testing.T) {
+	g := NewGomegaWithT(t)
+	logger.Initialize()
+
+	opts := &config.Opts{WebhookSecret: "secret", SignatureMode: config.SignatureModeQuery, SecretQueryParam: "token"}
+
+	status, _ := serve(opts, httptest.NewRequest(http.MethodPost, "/?token=secret", strings.NewReader(body)))
+	g.Expect(status).To(Equal(http.StatusOK))
+
+	status, _ = serve(opts, httptest.NewRequest(http.MethodPost, "/?token=guess", strings.NewReader(body)))
+	g.Expect(status).To(Equal(http.StatusUnauthorized))
+}
+
+func TestBasicAuth(t *testing.T) {
+	g := NewGomegaWithT(t)
+	logger.Initialize()
+
+	opts := &config.Opts{BasicAuthUser: "litmos", BasicAuthPassword: "pass"}
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.SetBasicAuth("litmos", "pass")
+	status, _ := serve(opts, req)
+	g.Expect(status).To(Equal(http.StatusOK))
+
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.SetBasicAuth("litmos", "wrong")
+	status, _ = serve(opts, req)
+	g.Expect(status).To(Equal(http.StatusUnauthorized))
+}
+
+func TestAllowedNetworks(t *testing.T) {
+	g := NewGomegaWithT(t)
+	logger.Initialize()
+
+	networks, err := config.ParseNetworks("10.0.0.0/8, 192.168.1.10")
+	g.Expect(err).Should(BeNil())
+	opts := &config.Opts{AllowedNetworks: networks, ClientIPHeader: "X-Forwarded-For"}
+
+	tests := []struct {
+		remoteAddr   string
+		forwardedFor string
+		status       int
+	}{
+		{"10.1.2.3:1234", "", http.StatusOK},
+		{"192.168.1.10:1234", "", http.StatusOK},
+		{"192.168.1.11:1234", "", http.StatusForbidden},
+		{"10.1.2.3:1234", "203.0.113.7", http.StatusForbidden},
+		{"10.1.2.3:1234", "203.0.113.7, 192.168.1.10", http.StatusOK},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.RemoteAddr = test.remoteAddr
+		if test.forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", test.forwardedFor)
+		}
+		status, _ := serve(opts, req)
+		g.Expect(status).To(Equal(test.status), "%s %s", test.remoteAddr, test.forwardedFor)
+	}
+
+	_, err = config.ParseNetworks("10.0.0.0/33")
+	g.Expect(err).ShouldNot(BeNil())
+}
+
+func TestNotConfigured(t *testing.T) {
+	g := NewGomegaWithT(t)
+	logger.Initialize()
+
+	status, _ := serve(&config.Opts{}, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	g.Expect(status).To(Equal(http.StatusUnauthorized))
+
+	status, received := serve(&config.Opts{InsecureSkipAuth: true}, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	g.Expect(status).To(Equal(http.StatusOK))
+	g.Expect(received).To(Equal(body))
+}
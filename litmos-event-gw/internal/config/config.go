@@ -3,6 +3,19 @@ package config
 import (
 	"flag"
 	"log"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	SignatureModeHeader = "header"
+	SignatureModeQuery  = "query"
+
+	UnmappedForward = "forward"
+	UnmappedDrop    = "drop"
+	UnmappedReject  = "reject"
 )
 
 type Opts struct {
@@ -11,6 +24,36 @@ type Opts struct {
 	EventPublishURL    string
 	BaseTopic          string
 	InSecureSkipVerify bool
+	WebhookSecret      string
+	SignatureMode      string
+	SignatureHeader    string
+	SecretQueryParam   string
+	BasicAuthUser      string
+	BasicAuthPassword  string
+	AllowedNetworks    []*net.IPNet
+	InsecureSkipAuth   bool
+	ClientIPHeader     string
+	EventMappingFile   string
+	UnmappedEvents     string
+	DefaultVersion     string
+	PublishTimeout     time.Duration
+	ManagementPort     int
+	Async              bool
+	QueueSize          int
+	Workers            int
+	MaxRetries         int
+	RetryBackoff       time.Duration
+	DrainTimeout       time.Duration
+	Enrich             bool
+	LitmosAPIURL       string
+	LitmosAPIKey       string
+	LitmosAPISource    string
+	EnrichObjects      string
+	EnrichTimeout      time.Duration
+	EnrichCacheTTL     time.Duration
+	EnrichCacheSize    int
+	BreakerFailures    int
+	BreakerCooldown    time.Duration
 }
 
 var GlobalConfig *Opts
@@ -21,19 +64,152 @@ func ParseFlags() {
 	eventPublishURL := flag.String("event-publish-url", "http://event-publish-service.kyma-system.svc.cluster.local:8080/v1/events", "URL to forward incoming events to Kyma Eventing")
 	baseTopic := flag.String("base-topic", "litmos", "Base Topic defined in the Async API specification")
 	insecureSkipVerify := flag.Bool("skip-tls-verify", false, "Skip TLS verify")
+	webhookSecret := flag.String("webhook-secret", "", "Shared secret of the Litmos webhook, signature verification is disabled if empty")
+	signatureMode := flag.String("signature-mode", SignatureModeHeader, "Verification of the webhook secret: header (HMAC-SHA256 signature of the body) or query (secret as query parameter)")
+	signatureHeader := flag.String("signature-header", "X-Litmos-Signature", "Header containing the HMAC-SHA256 signature of the body, hex or base64 encoded")
+	secretQueryParam := flag.String("secret-query-param", "secret", "Query parameter containing the shared secret")
+	basicAuthUser := flag.String("basic-auth-user", "", "User of the optional basic authentication")
+	basicAuthPassword := flag.String("basic-auth-password", "", "Password of the optional basic authentication")
+	allowedIPs := flag.String("allowed-ips", "", "Comma separated list of IPs or CIDR ranges the webhook calls are accepted from, all if empty")
+	insecureSkipAuth := flag.Bool("insecure-skip-auth", false, "Accept webhook calls without webhook secret, basic auth or allowed IPs. Not recommended for production")
+	clientIPHeader := flag.String("client-ip-header", "", "Header the ingress appends the client IP to, e.g. X-Forwarded-For. The remote address is used if empty")
+	eventMappingFile := flag.String("event-mapping-file", "", "JSON file mapping Litmos types and objects to Kyma event names and versions")
+	unmappedEvents := flag.String("unmapped-events", UnmappedForward, "Handling of Litmos events without mapping: forward (as <base-topic>.<type>), drop or reject (with 422)")
+	defaultVersion := flag.String("default-event-version", "v1", "Event version of forwarded Litmos events without mapping")
+	publishTimeout := flag.Duration("publish-timeout", 10*time.Second, "Timeout of publishing an event to Kyma, exceeding it is reported with 504")
+	managementPort := flag.Int("management-port", 8081, "Port serving health checks and metrics")
+	async := flag.Bool("async", false, "Acknowledge webhook calls with 202 once the event is queued, events are published by a worker pool")
+	queueSize := flag.Int("queue-size", 1000, "Maximum number of queued events in async mode, webhook calls are rejected with 503 if the queue is full")
+	workers := flag.Int("workers", 4, "Number of workers publishing queued events in async mode")
+	maxRetries := flag.Int("max-retries", 3, "Retries of publishing a queued event if the event bus fails")
+	retryBackoff := flag.Duration("retry-backoff", time.Second, "Wait before the first retry, doubled with every retry")
+	drainTimeout := flag.Duration("drain-timeout", 30*time.Second, "Maximum time to publish the queued events on shutdown")
+	enrich := flag.Bool("enrich", false, "Embed the users, courses and learning paths referenced by the event data, fetched from the Litmos API. "+
+		"The API key and source are read from the LITMOS_API_KEY and LITMOS_API_SOURCE environment variables")
+	litmosAPIURL := flag.String("litmos-api-url", "https://api.litmos.com/v1.svc", "URL of the Litmos API")
+	enrichObjects := flag.String("enrich-objects", "", "Comma separated list of Litmos objects of the events to enrich, all if empty")
+	enrichTimeout := flag.Duration("enrich-timeout", 5*time.Second, "Timeout of enriching an event, shared by all its calls to the Litmos API")
+	enrichCacheTTL := flag.Duration("enrich-cache-ttl", 5*time.Minute, "Time fetched entities are cached, 0 disables the cache")
+	enrichCacheSize := flag.Int("enrich-cache-size", 1000, "Maximum number of cached entities")
+	breakerFailures := flag.Int("breaker-failures", 5, "Consecutive failures of the Litmos API opening the circuit breaker")
+	breakerCooldown := flag.Duration("breaker-cooldown", 30*time.Second, "Time the circuit breaker stays open before the Litmos API is called again")
 	flag.Parse()
 
+	allowedNetworks, err := ParseNetworks(*allowedIPs)
+	if err != nil {
+		log.Panic("Invalid configuration - ", err)
+	}
+
 	GlobalConfig = &Opts{
 		LogRequest:         *logRequest,
 		AppName:            *appName,
 		EventPublishURL:    *eventPublishURL,
 		BaseTopic:          *baseTopic,
 		InSecureSkipVerify: *insecureSkipVerify,
+		WebhookSecret:      *webhookSecret,
+		SignatureMode:      *signatureMode,
+		SignatureHeader:    *signatureHeader,
+		SecretQueryParam:   *secretQueryParam,
+		BasicAuthUser:      *basicAuthUser,
+		BasicAuthPassword:  *basicAuthPassword,
+		AllowedNetworks:    allowedNetworks,
+		InsecureSkipAuth:   *insecureSkipAuth,
+		ClientIPHeader:     *clientIPHeader,
+		EventMappingFile:   *eventMappingFile,
+		UnmappedEvents:     *unmappedEvents,
+		DefaultVersion:     *defaultVersion,
+		PublishTimeout:     *publishTimeout,
+		ManagementPort:     *managementPort,
+		Async:              *async,
+		QueueSize:          *queueSize,
+		Workers:            *workers,
+		MaxRetries:         *maxRetries,
+		RetryBackoff:       *retryBackoff,
+		DrainTimeout:       *drainTimeout,
+		Enrich:             *enrich,
+		LitmosAPIURL:       *litmosAPIURL,
+		LitmosAPIKey:       os.Getenv("LITMOS_API_KEY"),
+		LitmosAPISource:    os.Getenv("LITMOS_API_SOURCE"),
+		EnrichObjects:      *enrichObjects,
+		EnrichTimeout:      *enrichTimeout,
+		EnrichCacheTTL:     *enrichCacheTTL,
+		EnrichCacheSize:    *enrichCacheSize,
+		BreakerFailures:    *breakerFailures,
+		BreakerCooldown:    *breakerCooldown,
 	}
 
 	if GlobalConfig.AppName == "" {
 		log.Panic("Invalid configuration - Missing APP Name", "config", GlobalConfig)
 	}
 
-	log.Println("App config", "config", GlobalConfig)
+	if GlobalConfig.SignatureMode != SignatureModeHeader && GlobalConfig.SignatureMode != SignatureModeQuery {
+		log.Panic("Invalid configuration - Unknown signature mode ", GlobalConfig.SignatureMode)
+	}
+
+	if (GlobalConfig.BasicAuthUser == "") != (GlobalConfig.BasicAuthPassword == "") {
+		log.Panic("Invalid configuration - Basic auth requires user and password")
+	}
+
+	if !GlobalConfig.AuthConfigured() && !GlobalConfig.InsecureSkipAuth {
+		log.Panic("Invalid configuration - Webhook secret, basic auth or allowed IPs required, use -insecure-skip-auth to accept all calls")
+	}
+
+	if GlobalConfig.UnmappedEvents != UnmappedForward && GlobalConfig.UnmappedEvents != UnmappedDrop &&
+		GlobalConfig.UnmappedEvents != UnmappedReject {
+		log.Panic("Invalid configuration - Unknown handling of unmapped events ", GlobalConfig.UnmappedEvents)
+	}
+
+	if GlobalConfig.Async && (GlobalConfig.QueueSize < 1 || GlobalConfig.Workers < 1 || GlobalConfig.MaxRetries < 0) {
+		log.Panic("Invalid configuration - Async mode requires a queue size and workers of at least 1")
+	}
+
+	if GlobalConfig.Enrich && (GlobalConfig.LitmosAPIKey == "" || GlobalConfig.LitmosAPISource == "") {
+		log.Panic("Invalid configuration - Enrichment requires LITMOS_API_KEY and LITMOS_API_SOURCE")
+	}
+
+	if GlobalConfig.Enrich && GlobalConfig.BreakerFailures < 1 {
+		log.Panic("Invalid configuration - Circuit breaker requires at least 1 failure")
+	}
+
+	log.Println("App config", "app-name", GlobalConfig.AppName, "event-publish-url", GlobalConfig.EventPublishURL,
+		"base-topic", GlobalConfig.BaseTopic, "signature-mode", GlobalConfig.SignatureMode, "allowed-ips", *allowedIPs,
+		"event-mapping-file", GlobalConfig.EventMappingFile, "unmapped-events", GlobalConfig.UnmappedEvents,
+		"async", GlobalConfig.Async, "queue-size", GlobalConfig.QueueSize, "workers", GlobalConfig.Workers,
+		"enrich", GlobalConfig.Enrich, "litmos-api-url", GlobalConfig.LitmosAPIURL)
+}
+
+// AuthConfigured reports if webhook calls are authenticated by secret, basic auth or their address
+func (o *Opts) AuthConfigured() bool {
+	return o.WebhookSecret != "" || o.BasicAuthUser != "" || len(o.AllowedNetworks) > 0
+}
+
+// ParseNetworks parses a comma separated list of IPs and CIDR ranges, single IPs are converted to host ranges
+func ParseNetworks(list string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, &net.ParseError{Type: "IP address", Text: entry}
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
 }
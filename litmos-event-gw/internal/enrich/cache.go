@@ -0,0 +1,56 @@
+package enrich
+
+import (
+	"sync"
+	"time"
+)
+
+type cacheEntry struct {
+	entity  interface{}
+	expires time.Time
+}
+
+// cache keeps the fetched entities for the ttl, it is cleared if it is full of unexpired entries
+type cache struct {
+	lock       sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]cacheEntry
+}
+
+func newCache(ttl time.Duration, maxEntries int) *cache {
+	return &cache{ttl: ttl, maxEntries: maxEntries, entries: map[string]cacheEntry{}}
+}
+
+func (c *cache) get(key string) (interface{}, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.entity, true
+}
+
+func (c *cache) put(key string, entity interface{}) {
+	if c.ttl <= 0 || c.maxEntries <= 0 {
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := time.Now()
+	if len(c.entries) >= c.maxEntries {
+		for k, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= c.maxEntries {
+			c.entries = map[string]cacheEntry{}
+		}
+	}
+	c.entries[key] = cacheEntry{entity: entity, expires: now.Add(c.ttl)}
+}
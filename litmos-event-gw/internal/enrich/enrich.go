@@ -0,0 +1,164 @@
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/config"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/metrics"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/model/events"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/tracing"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// reference is an id in the event data of an entity of the Litmos API, the entity is embedded in the data
+type reference struct {
+	field    string
+	resource string
+	embed    string
+}
+
+var references = []reference{
+	{field: "userId", resource: "users", embed: "user"},
+	{field: "courseId", resource: "courses", embed: "course"},
+	{field: "learningPathId", resource: "learningpaths", embed: "learningPath"},
+}
+
+// errNotFound is not counted as failure of the API
+var errNotFound = fmt.Errorf("entity not found")
+
+// Enricher fetches the entities referenced by the event data from the Litmos API
+type Enricher struct {
+	client  *http.Client
+	timeout time.Duration
+	apiURL  string
+	apiKey  string
+	source  string
+	objects map[string]bool
+	cache   *cache
+	breaker *breaker
+}
+
+func New(opts *config.Opts) *Enricher {
+	objects := map[string]bool{}
+	for _, object := range strings.Split(opts.EnrichObjects, ",") {
+		if object = strings.TrimSpace(object); object != "" {
+			objects[object] = true
+		}
+	}
+
+	return &Enricher{
+		client:  &http.Client{},
+		timeout: opts.EnrichTimeout,
+		apiURL:  strings.TrimSuffix(opts.LitmosAPIURL, "/"),
+		apiKey:  opts.LitmosAPIKey,
+		source:  opts.LitmosAPISource,
+		objects: objects,
+		cache:   newCache(opts.EnrichCacheTTL, opts.EnrichCacheSize),
+		breaker: newBreaker(opts.BreakerFailures, opts.BreakerCooldown),
+	}
+}
+
+// Enrich embeds the referenced entities in the event data. All lookups of the event share a single deadline,
+// entities which can't be fetched before it are left out and the event is published anyway
+func (e *Enricher) Enrich(ctx context.Context, event *events.KymaEvent) {
+	if len(e.objects) > 0 && (event.Litmos == nil || !e.objects[event.Litmos.Object]) {
+		return
+	}
+	data, ok := event.Data.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	log := tracing.Logger(ctx)
+	for _, ref := range references {
+		id, ok := data[ref.field].(string)
+		if !ok || id == "" {
+			continue
+		}
+		if _, exists := data[ref.embed]; exists {
+			continue
+		}
+
+		if ctx.Err() != nil {
+			log.Warnw("enriching event aborted", "event-id", event.EventID, "error", ctx.Err())
+			return
+		}
+
+		entity, err := e.fetch(ctx, ref.resource, id)
+		if err != nil {
+			log.Warnw("enriching event failed", "event-id", event.EventID, "resource", ref.resource, "id", id, "error", err)
+			continue
+		}
+		data[ref.embed] = entity
+	}
+}
+
+func (e *Enricher) fetch(ctx context.Context, resource string, id string) (interface{}, error) {
+	path := "/" + resource + "/" + url.PathEscape(id)
+	if entity, ok := e.cache.get(path); ok {
+		metrics.EnrichmentLookups.WithLabelValues(metrics.LookupCached).Inc()
+		return entity, nil
+	}
+
+	if !e.breaker.allow() {
+		metrics.EnrichmentLookups.WithLabelValues(metrics.LookupCircuitOpen).Inc()
+		return nil, fmt.Errorf("circuit breaker of the Litmos API is open")
+	}
+
+	entity, err := e.get(ctx, path)
+	switch {
+	case err == errNotFound:
+		e.breaker.success()
+		metrics.EnrichmentLookups.WithLabelValues(metrics.LookupNotFound).Inc()
+		return nil, err
+	case err != nil:
+		e.breaker.failure()
+		metrics.EnrichmentLookups.WithLabelValues(metrics.LookupError).Inc()
+		return nil, err
+	}
+
+	e.breaker.success()
+	e.cache.put(path, entity)
+	metrics.EnrichmentLookups.WithLabelValues(metrics.LookupFetched).Inc()
+	return entity, nil
+}
+
+func (e *Enricher) get(ctx context.Context, path string) (interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.apiURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{"source": {e.source}, "format": {"json"}}
+	req.URL.RawQuery = query.Encode()
+	req.Header.Set("apikey", e.apiKey)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("unexpected response of the Litmos API %d: %s", resp.StatusCode, string(body))
+	}
+
+	var entity interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&entity); err != nil {
+		return nil, fmt.Errorf("invalid response of the Litmos API: %s", err.Error())
+	}
+	return entity, nil
+}
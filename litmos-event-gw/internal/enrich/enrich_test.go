@@ -0,0 +1,148 @@
+package enrich
+
+import (
+	"context"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/config"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/logger"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/model/events"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func stubAPI(calls *int32, failing *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		if r.Header.Get("apikey") != "key" || r.URL.Query().Get("source") != "gateway" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if atomic.LoadInt32(failing) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1.svc/users/yj-nr8PhW8o1":
+			_, _ = w.Write([]byte(`{"Id":"yj-nr8PhW8o1","UserName":"sample"}`))
+		case "/v1.svc/courses/nAcqwEA8jUo1":
+			_, _ = w.Write([]byte(`{"Id":"nAcqwEA8jUo1","Name":"Course Demo"}`))
+		case "/v1.svc/users/slow":
+			time.Sleep(200 * time.Millisecond)
+			_, _ = w.Write([]byte(`{"Id":"slow"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func opts(apiURL string) *config.Opts {
+	return &config.Opts{
+		LitmosAPIURL:    apiURL + "/v1.svc",
+		LitmosAPIKey:    "key",
+		LitmosAPISource: "gateway",
+		EnrichTimeout:   time.Second,
+		EnrichCacheTTL:  time.Minute,
+		EnrichCacheSize: 10,
+		BreakerFailures: 2,
+		BreakerCooldown: 50 * time.Millisecond,
+	}
+}
+
+func event(data map[string]interface{}) *events.KymaEvent {
+	return &events.KymaEvent{EventID: "1", Data: data, Litmos: &events.LitmosMetadata{Object: "event"}}
+}
+
+func TestEnrich(t *testing.T) {
+	g := NewGomegaWithT(t)
+	logger.Initialize()
+
+	var calls, failing int32
+	api := stubAPI(&calls, &failing)
+	defer api.Close()
+
+	e := New(opts(api.URL))
+
+	data := map[string]interface{}{"userId": "yj-nr8PhW8o1", "courseId": "nAcqwEA8jUo1", "learningPathId": "unknown"}
+	e.Enrich(context.Background(), event(data))
+	g.Expect(data["user"]).To(Equal(map[string]interface{}{"Id": "yj-nr8PhW8o1", "UserName": "sample"}))
+	g.Expect(data["course"]).To(Equal(map[string]interface{}{"Id": "nAcqwEA8jUo1", "Name": "Course Demo"}))
+	g.Expect(data).NotTo(HaveKey("learningPath"))
+	g.Expect(atomic.LoadInt32(&calls)).To(Equal(int32(3)))
+
+	data = map[string]interface{}{"userId": "yj-nr8PhW8o1"}
+	e.Enrich(context.Background(), event(data))
+	g.Expect(data).To(HaveKey("user"))
+	g.Expect(atomic.LoadInt32(&calls)).To(Equal(int32(3)))
+}
+
+func TestEnrichObjects(t *testing.T) {
+	g := NewGomegaWithT(t)
+	logger.Initialize()
+
+	var calls, failing int32
+	api := stubAPI(&calls, &failing)
+	defer api.Close()
+
+	o := opts(api.URL)
+	o.EnrichObjects = "user, course"
+	e := New(o)
+
+	data := map[string]interface{}{"userId": "yj-nr8PhW8o1"}
+	e.Enrich(context.Background(), event(data))
+	g.Expect(data).NotTo(HaveKey("user"))
+	g.Expect(atomic.LoadInt32(&calls)).To(Equal(int32(0)))
+}
+
+func TestEnrichDeadline(t *testing.T) {
+	g := NewGomegaWithT(t)
+	logger.Initialize()
+
+	var calls, failing int32
+	api := stubAPI(&calls, &failing)
+	defer api.Close()
+
+	o := opts(api.URL)
+	o.EnrichTimeout = 50 * time.Millisecond
+	e := New(o)
+
+	start := time.Now()
+	data := map[string]interface{}{"userId": "slow", "courseId": "nAcqwEA8jUo1"}
+	e.Enrich(context.Background(), event(data))
+	g.Expect(time.Since(start)).To(BeNumerically("<", 150*time.Millisecond))
+	g.Expect(data).NotTo(HaveKey("user"))
+	g.Expect(data).NotTo(HaveKey("course"))
+	g.Expect(atomic.LoadInt32(&calls)).To(Equal(int32(1)))
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	g := NewGomegaWithT(t)
+	logger.Initialize()
+
+	var calls int32
+	failing := int32(1)
+	api := stubAPI(&calls, &failing)
+	defer api.Close()
+
+	o := opts(api.URL)
+	o.EnrichCacheTTL = 0
+	e := New(o)
+
+	for i := 0; i < 4; i++ {
+		data := map[string]interface{}{"userId": "yj-nr8PhW8o1"}
+		e.Enrich(context.Background(), event(data))
+		g.Expect(data).NotTo(HaveKey("user"))
+	}
+	g.Expect(atomic.LoadInt32(&calls)).To(Equal(int32(2)))
+
+	time.Sleep(60 * time.Millisecond)
+	atomic.StoreInt32(&failing, 0)
+
+	data := map[string]interface{}{"userId": "yj-nr8PhW8o1"}
+	e.Enrich(context.Background(), event(data))
+	g.Expect(data).To(HaveKey("user"))
+	g.Expect(atomic.LoadInt32(&calls)).To(Equal(int32(3)))
+}
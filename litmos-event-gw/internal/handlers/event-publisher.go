@@ -1,43 +1,101 @@
 package handlers
 
 import (
+	"context"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/config"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/enrich"
 	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/incoming"
-	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/logger"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/mapper"
 	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/model/errors"
 	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/outgoing"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/queue"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/tracing"
 	"io/ioutil"
 	"net/http"
 )
 
 type EventPublisher struct {
-	eventForwarder *outgoing.EventForwarder
+	eventForwarder queue.Forwarder
+	eventMapper    *mapper.Mapper
+	// enricher is set if enrichment is enabled in sync mode
+	enricher *enrich.Enricher
+	// queue is set in async mode
+	queue *queue.Queue
 }
 
-func NewEventPublisher() *EventPublisher {
-	return &EventPublisher{eventForwarder: outgoing.NewEventForwarder()}
+func NewEventPublisher() (*EventPublisher, error) {
+	eventMapper, err := mapper.New(config.GlobalConfig.EventMappingFile)
+	if err != nil {
+		return nil, err
+	}
+
+	ep := &EventPublisher{eventForwarder: outgoing.NewEventForwarder(), eventMapper: eventMapper}
+	if !config.GlobalConfig.Async {
+		if config.GlobalConfig.Enrich {
+			ep.enricher = enrich.New(config.GlobalConfig)
+		}
+		return ep, nil
+	}
+
+	// queued events are enriched by the workers
+	var enricher queue.Enricher
+	if config.GlobalConfig.Enrich {
+		enricher = enrich.New(config.GlobalConfig)
+	}
+	ep.queue = queue.New(ep.eventForwarder, enricher, config.GlobalConfig.QueueSize, config.GlobalConfig.Workers,
+		config.GlobalConfig.MaxRetries, config.GlobalConfig.RetryBackoff)
+	return ep, nil
 }
+
+// Shutdown publishes the queued events in async mode
+func (ep *EventPublisher) Shutdown(ctx context.Context) error {
+	if ep.queue == nil {
+		return nil
+	}
+	return ep.queue.Close(ctx)
+}
+
 func (ep *EventPublisher) EventHandler() http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		body, err := ioutil.ReadAll(r.Body)
 		defer r.Body.Close()
 		if err != nil {
-			logger.Logger.Errorw("error when parsing request", "error", err)
+			errors.Handle(w, r, errors.New(errors.BadInput, "reading request body failed", err))
+			return
 		}
-		logger.Logger.Infow("event request body", "event", string(body))
+		log := tracing.Logger(r.Context())
+		log.Infow("event request body", "event", string(body))
 
-		kymaEvent, err := incoming.Process(body)
+		kymaEvent, err := incoming.Process(r.Context(), body, ep.eventMapper)
+		if err == incoming.ErrDropped {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
 		if err != nil {
-			errors.HandleError(w, err, errors.InternalError)
+			errors.Handle(w, r, err)
+			return
+		}
+
+		if ep.enricher != nil {
+			ep.enricher.Enrich(r.Context(), kymaEvent)
+		}
+
+		if ep.queue != nil {
+			if err := ep.queue.Enqueue(r.Context(), kymaEvent); err != nil {
+				errors.Handle(w, r, err)
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
 			return
 		}
 
-		resp, err := ep.eventForwarder.Forward(kymaEvent)
+		resp, err := ep.eventForwarder.Forward(r.Context(), kymaEvent)
 		if err != nil {
-			errors.HandleError(w, err, errors.InternalError)
+			errors.Handle(w, r, err)
 			return
 		}
 
-		logger.Logger.Infow("Received response for event publishing", "response", resp)
+		log.Infow("Received response for event publishing", "response", resp)
 
 		w.WriteHeader(http.StatusOK)
 	})
@@ -1,19 +1,43 @@
 package incoming
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/logger"
+	"fmt"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/config"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/mapper"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/metrics"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/model/errors"
 	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/model/events"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/tracing"
 )
 
-func Process(requestBody []byte) (*events.KymaEvent, error) {
+// ErrDropped is returned for Litmos events without mapping if unmapped events are dropped
+var ErrDropped = fmt.Errorf("unmapped Litmos event dropped")
+
+func Process(ctx context.Context, requestBody []byte, eventMapper *mapper.Mapper) (*events.KymaEvent, error) {
 	le, err := to(requestBody)
 	if err != nil {
 		return nil, err
 	}
+	eventType, eventTypeVersion, err := eventMapper.Map(le.Type, le.Object)
+	if err != nil {
+		metrics.EventsReceived.WithLabelValues(metrics.TypeOther).Inc()
+		switch config.GlobalConfig.UnmappedEvents {
+		case config.UnmappedDrop:
+			tracing.Logger(ctx).Warnw("dropping Litmos event", "litmos-id", le.ID, "error", err)
+			metrics.EventsDropped.WithLabelValues(metrics.ReasonUnmapped).Inc()
+			return nil, ErrDropped
+		case config.UnmappedReject:
+			return nil, errors.New(errors.UnknownEventType, fmt.Sprintf("no mapping for Litmos type %q", le.Type), err)
+		}
+		eventType, eventTypeVersion = config.GlobalConfig.BaseTopic+"."+le.Type, config.GlobalConfig.DefaultVersion
+	} else {
+		metrics.EventsReceived.WithLabelValues(le.Type).Inc()
+	}
 
-	ke := events.Map(le)
-	logger.Logger.Infow("kyma Event", "kyma-event", ke)
+	ke := events.Map(le, eventType, eventTypeVersion)
+	tracing.Logger(ctx).Infow("kyma Event", "kyma-event", ke)
 
 	return ke, nil
 }
@@ -22,7 +46,10 @@ func to(requestBody []byte) (*events.LitmosEvent, error) {
 	le := events.LitmosEvent{}
 	err := json.Unmarshal(requestBody, &le)
 	if err != nil {
-		return nil, err
+		return nil, errors.New(errors.BadInput, "body is not a valid Litmos event", err)
+	}
+	if le.Type == "" {
+		return nil, errors.New(errors.BadInput, "Litmos event without type", nil)
 	}
 
 	return &le, nil
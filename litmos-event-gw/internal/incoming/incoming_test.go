@@ -0,0 +1,68 @@
+package incoming
+
+import (
+	"context"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/config"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/logger"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/mapper"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/metrics"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/model/errors"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"testing"
+)
+
+func TestProcessUnmapped(t *testing.T) {
+	g := NewGomegaWithT(t)
+	logger.Initialize()
+
+	eventMapper, err := mapper.New("../../assets/event-mapping.json")
+	g.Expect(err).Should(BeNil())
+
+	config.GlobalConfig = &config.Opts{AppName: "litmos", BaseTopic: "litmos", UnmappedEvents: config.UnmappedForward, DefaultVersion: "v1"}
+	mapped := testutil.ToFloat64(metrics.EventsReceived.WithLabelValues("course.passed"))
+	other := testutil.ToFloat64(metrics.EventsReceived.WithLabelValues(metrics.TypeOther))
+
+	ke, err := Process(context.Background(), []byte(`{"id":1,"type":"course.passed","object":"event"}`), eventMapper)
+	g.Expect(err).Should(BeNil())
+	g.Expect(ke.EventType).To(Equal("course.completed"))
+	g.Expect(ke.EventTypeVersion).To(Equal("v2"))
+
+	ke, err = Process(context.Background(), []byte(`{"id":2,"type":"login","object":"event"}`), eventMapper)
+	g.Expect(err).Should(BeNil())
+	g.Expect(ke.EventType).To(Equal("litmos.login"))
+	g.Expect(ke.EventTypeVersion).To(Equal("v1"))
+
+	config.GlobalConfig.UnmappedEvents = config.UnmappedDrop
+	ke, err = Process(context.Background(), []byte(`{"id":3,"type":"login","object":"event"}`), eventMapper)
+	g.Expect(err).To(Equal(ErrDropped))
+	g.Expect(ke).Should(BeNil())
+
+	g.Expect(testutil.ToFloat64(metrics.EventsReceived.WithLabelValues("course.passed"))).To(Equal(mapped + 1))
+	g.Expect(testutil.ToFloat64(metrics.EventsReceived.WithLabelValues(metrics.TypeOther))).To(Equal(other + 2))
+}
+
+func TestProcessErrors(t *testing.T) {
+	g := NewGomegaWithT(t)
+	logger.Initialize()
+
+	eventMapper, err := mapper.New("../../assets/event-mapping.json")
+	g.Expect(err).Should(BeNil())
+
+	config.GlobalConfig = &config.Opts{AppName: "litmos", BaseTopic: "litmos", UnmappedEvents: config.UnmappedReject}
+
+	tests := []struct {
+		body      string
+		errorType errors.ErrorType
+	}{
+		{`{"id":1,"type":`, errors.BadInput},
+		{`{"id":1,"object":"event"}`, errors.BadInput},
+		{`{"id":1,"type":"login","object":"event"}`, errors.UnknownEventType},
+	}
+
+	for _, test := range tests {
+		_, err := Process(context.Background(), []byte(test.body), eventMapper)
+		g.Expect(err).To(BeAssignableToTypeOf(&errors.Error{}), test.body)
+		g.Expect(err.(*errors.Error).Type).To(Equal(test.errorType), test.body)
+	}
+}
@@ -0,0 +1,76 @@
+package management
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/config"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const dialTimeout = 2 * time.Second
+
+// New serves health checks and metrics on the management port
+func New(opts *config.Opts) (*http.Server, error) {
+	publishURL, err := url.Parse(opts.EventPublishURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid event publish url %q: %s", opts.EventPublishURL, err.Error())
+	}
+
+	serveMux := http.NewServeMux()
+	serveMux.HandleFunc("/healthz", healthz)
+	serveMux.HandleFunc("/ready", ready(publishURL))
+	serveMux.Handle("/metrics", promhttp.Handler())
+
+	return &http.Server{
+		Addr:    fmt.Sprintf(":%d", opts.ManagementPort),
+		Handler: serveMux,
+	}, nil
+}
+
+// healthz answers as long as the process is alive
+func healthz(w http.ResponseWriter, r *http.Request) {
+	writeStatus(w, http.StatusOK, nil)
+}
+
+// ready fails if the event bus is not reachable, events could not be published anyway
+func ready(publishURL *url.URL) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := checkReachable(publishURL); err != nil {
+			writeStatus(w, http.StatusServiceUnavailable, err)
+			return
+		}
+		writeStatus(w, http.StatusOK, nil)
+	}
+}
+
+func checkReachable(target *url.URL) error {
+	port := target.Port()
+	if port == "" {
+		port = "80"
+		if strings.EqualFold(target.Scheme, "https") {
+			port = "443"
+		}
+	}
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(target.Hostname(), port), dialTimeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func writeStatus(w http.ResponseWriter, status int, err error) {
+	body := map[string]string{"status": "ok"}
+	if err != nil {
+		body = map[string]string{"status": "error", "error": err.Error()}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,117 @@
+package mapper
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+)
+
+type mappingConfig struct {
+	LitmosType        string `json:"litmosType"`
+	LitmosTypeRegex   string `json:"litmosTypeRegex"`
+	LitmosObject      string `json:"litmosObject"`
+	LitmosObjectRegex string `json:"litmosObjectRegex"`
+	KymaEventName     string `json:"kymaEventName"`
+	KymaEventVersion  string `json:"kymaEventVersion"`
+}
+
+type mapping struct {
+	litmosType       *regexp.Regexp
+	litmosObject     *regexp.Regexp
+	kymaEventName    string
+	kymaEventVersion string
+}
+
+// Mapper maps the type and object of Litmos events to Kyma event names and versions, the first matching mapping wins
+type Mapper struct {
+	mappings []mapping
+}
+
+// New reads the mapping file, without file no Litmos event is mapped
+func New(file string) (*Mapper, error) {
+	if file == "" {
+		return &Mapper{}, nil
+	}
+
+	mappingFile, err := os.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("error opening event mapping config: %s", err.Error())
+	}
+	//noinspection GoUnhandledErrorResult
+	defer mappingFile.Close()
+
+	mappingData, err := ioutil.ReadAll(mappingFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading event mapping config: %s", err.Error())
+	}
+
+	var mappingConfigList []mappingConfig
+	if err := json.Unmarshal(mappingData, &mappingConfigList); err != nil {
+		return nil, fmt.Errorf("error in event mapping config json: %s", err.Error())
+	}
+
+	result := make([]mapping, len(mappingConfigList))
+	for i, currentConfig := range mappingConfigList {
+		if currentConfig.KymaEventName == "" || currentConfig.KymaEventVersion == "" {
+			return nil, fmt.Errorf("missing \"kymaEventName\" or \"kymaEventVersion\" at config item %d", i)
+		}
+
+		litmosType, err := matcher("litmosType", currentConfig.LitmosType, currentConfig.LitmosTypeRegex, i)
+		if err != nil {
+			return nil, err
+		}
+		if litmosType == nil {
+			return nil, fmt.Errorf("missing \"litmosType\" or \"litmosTypeRegex\" at config item %d", i)
+		}
+
+		litmosObject, err := matcher("litmosObject", currentConfig.LitmosObject, currentConfig.LitmosObjectRegex, i)
+		if err != nil {
+			return nil, err
+		}
+
+		result[i] = mapping{
+			litmosType:       litmosType,
+			litmosObject:     litmosObject,
+			kymaEventName:    currentConfig.KymaEventName,
+			kymaEventVersion: currentConfig.KymaEventVersion,
+		}
+	}
+
+	return &Mapper{mappings: result}, nil
+}
+
+// matcher compiles the regex of a field, exact values are quoted and anchored
+func matcher(field string, exact string, regex string, item int) (*regexp.Regexp, error) {
+	if exact != "" && regex != "" {
+		return nil, fmt.Errorf("only one of %q and \"%sRegex\" allowed at config item %d", field, field, item)
+	}
+	if exact != "" {
+		return regexp.MustCompile("^" + regexp.QuoteMeta(exact) + "$"), nil
+	}
+	if regex == "" {
+		return nil, nil
+	}
+
+	compiled, err := regexp.Compile(regex)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing \"%sRegex\": %q at config item %d: %s", field, regex, item, err.Error())
+	}
+	return compiled, nil
+}
+
+// Map returns the Kyma event name and version of the first mapping matching type and object
+func (m *Mapper) Map(litmosType string, litmosObject string) (eventName string, eventVersion string, err error) {
+	for _, current := range m.mappings {
+		if !current.litmosType.MatchString(litmosType) {
+			continue
+		}
+		if current.litmosObject != nil && !current.litmosObject.MatchString(litmosObject) {
+			continue
+		}
+		return current.kymaEventName, current.kymaEventVersion, nil
+	}
+
+	return eventName, eventVersion, fmt.Errorf("no event mapping found for Litmos type %q and object %q", litmosType, litmosObject)
+}
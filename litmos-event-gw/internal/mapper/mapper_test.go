@@ -0,0 +1,72 @@
+package mapper
+
+import (
+	. "github.com/onsi/gomega"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestMap(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	m, err := New("../../assets/event-mapping.json")
+	g.Expect(err).Should(BeNil())
+
+	tests := []struct {
+		litmosType   string
+		litmosObject string
+		eventName    string
+		eventVersion string
+	}{
+		{"achievement.earned", "event", "achievement.earned", "v1"},
+		{"course.passed", "event", "course.completed", "v2"},
+		{"user.created", "user", "user.changed", "v1"},
+	}
+
+	for _, test := range tests {
+		eventName, eventVersion, err := m.Map(test.litmosType, test.litmosObject)
+		g.Expect(err).Should(BeNil())
+		g.Expect(eventName).To(Equal(test.eventName))
+		g.Expect(eventVersion).To(Equal(test.eventVersion))
+	}
+
+	for _, unmapped := range [][]string{{"achievement.earned.x", "event"}, {"course.passed", "course"}, {"login", "event"}} {
+		_, _, err := m.Map(unmapped[0], unmapped[1])
+		g.Expect(err).ShouldNot(BeNil(), "%v", unmapped)
+	}
+}
+
+func TestNoMappingFile(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	m, err := New("")
+	g.Expect(err).Should(BeNil())
+
+	_, _, err = m.Map("achievement.earned", "event")
+	g.Expect(err).ShouldNot(BeNil())
+}
+
+func TestInvalidMapping(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	for _, mapping := range []string{
+		`{"litmosType": "achievement.earned"}`,
+		`[{"litmosType": "achievement.earned", "kymaEventName": "achievement.earned"}]`,
+		`[{"litmosObject": "event", "kymaEventName": "achievement.earned", "kymaEventVersion": "v1"}]`,
+		`[{"litmosType": "a", "litmosTypeRegex": "a", "kymaEventName": "a", "kymaEventVersion": "v1"}]`,
+		`[{"litmosTypeRegex": "(", "kymaEventName": "a", "kymaEventVersion": "v1"}]`,
+	} {
+		file, err := ioutil.TempFile("", "event-mapping")
+		g.Expect(err).Should(BeNil())
+		_, _ = file.WriteString(mapping)
+		_ = file.Close()
+
+		_, err = New(file.Name())
+		g.Expect(err).ShouldNot(BeNil(), mapping)
+		_ = os.Remove(file.Name())
+	}
+
+	_, err := New("missing.json")
+	g.Expect(err).ShouldNot(BeNil())
+}
@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/model/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"time"
+)
+
+const (
+	ReasonUnmapped      = "unmapped"
+	ReasonQueueFull     = "queue_full"
+	ReasonPublishFailed = "publish_failed"
+
+	ResultSuccess = "success"
+
+	// TypeOther labels the received Litmos events of types without mapping
+	TypeOther = "other"
+
+	LookupCached      = "cached"
+	LookupFetched     = "fetched"
+	LookupNotFound    = "not_found"
+	LookupError       = "error"
+	LookupCircuitOpen = "circuit_open"
+)
+
+// results label the forward outcomes by the type of the error
+var results = map[errors.ErrorType]string{
+	errors.InternalError:       "error",
+	errors.UpstreamError:       "upstream_error",
+	errors.UpstreamUnavailable: "upstream_unavailable",
+	errors.UpstreamTimeout:     "upstream_timeout",
+}
+
+// EventsReceived counts the Litmos events received, by their Litmos type. Types without mapping are counted
+// as other, so the label values are bound by the event mapping and not by the payloads.
+var EventsReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "litmos_events_received_total",
+	Help: "Litmos events received, by Litmos type",
+}, []string{"litmos_type"})
+
+// EventsDropped counts the Litmos events acknowledged without being published to Kyma
+var EventsDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "litmos_events_dropped_total",
+	Help: "Litmos events acknowledged without publishing them to Kyma",
+}, []string{"reason"})
+
+// EventsRejected counts the Litmos events rejected with a retryable status, Litmos redelivers them
+var EventsRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "litmos_events_rejected_total",
+	Help: "Litmos events rejected for a redelivery",
+}, []string{"reason"})
+
+// QueueDepth is the number of events waiting to be published in async mode
+var QueueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "litmos_queue_depth",
+	Help: "Events waiting in the queue to be published to Kyma",
+})
+
+// EventsForwarded counts the events published to Kyma, by outcome
+var EventsForwarded = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "litmos_events_forwarded_total",
+	Help: "Events published to Kyma, by result",
+}, []string{"result"})
+
+// ForwardDuration is the latency of publishing events to Kyma
+var ForwardDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "litmos_event_forward_duration_seconds",
+	Help:    "Duration of publishing events to Kyma, by result",
+	Buckets: prometheus.DefBuckets,
+}, []string{"result"})
+
+// EnrichmentLookups counts the lookups of entities in the Litmos API, by result
+var EnrichmentLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "litmos_enrichment_lookups_total",
+	Help: "Lookups of entities referenced by events in the Litmos API, by result",
+}, []string{"result"})
+
+// RequestDuration is the latency of the webhook calls, by response status
+var RequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "litmos_http_request_duration_seconds",
+	Help:    "Duration of the webhook calls, by response code",
+	Buckets: prometheus.DefBuckets,
+}, []string{"code"})
+
+// ObserveForward records the outcome and the latency of publishing an event
+func ObserveForward(start time.Time, err error) {
+	result := ResultSuccess
+	if err != nil {
+		result = results[errors.InternalError]
+		if typed, ok := err.(*errors.Error); ok {
+			if typedResult, ok := results[typed.Type]; ok {
+				result = typedResult
+			}
+		}
+	}
+
+	EventsForwarded.WithLabelValues(result).Inc()
+	ForwardDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+}
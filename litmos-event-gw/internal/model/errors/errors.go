@@ -1,7 +1,9 @@
 package errors
 
 import (
-	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/logger"
+	stderrors "errors"
+	"fmt"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/tracing"
 	"net/http"
 )
 
@@ -11,18 +13,84 @@ const (
 	InternalError ErrorType = iota
 	BadInput
 	UnAuthorized
+	Forbidden
+	UnknownEventType
+	UpstreamError
+	UpstreamUnavailable
+	UpstreamTimeout
 )
 
-func HandleError(writer http.ResponseWriter, err error, errorType ErrorType) {
+var responses = map[ErrorType]struct {
+	status  int
+	message string
+}{
+	InternalError:       {http.StatusInternalServerError, "Internal Server Error"},
+	BadInput:            {http.StatusBadRequest, "Invalid input"},
+	UnAuthorized:        {http.StatusUnauthorized, "UnAuthorized"},
+	Forbidden:           {http.StatusForbidden, "Forbidden"},
+	UnknownEventType:    {http.StatusUnprocessableEntity, "Unknown event type"},
+	UpstreamError:       {http.StatusBadGateway, "Event publishing failed"},
+	UpstreamUnavailable: {http.StatusServiceUnavailable, "Event publishing unavailable"},
+	UpstreamTimeout:     {http.StatusGatewayTimeout, "Event publishing timed out"},
+}
+
+// Error determines the status of the response by its type, the reason is sent to the client
+type Error struct {
+	Type   ErrorType
+	Reason string
+	// UpstreamStatus is the status the event bus responded with, 0 if it didn't respond
+	UpstreamStatus int
+	Err            error
+}
+
+func New(errorType ErrorType, reason string, err error) *Error {
+	return &Error{Type: errorType, Reason: reason, Err: err}
+}
+
+// Upstream classifies an error response of the event bus, unavailability is reported as 503, everything else as 502
+func Upstream(status int, reason string) *Error {
+	errorType := UpstreamError
+	if status == http.StatusServiceUnavailable || status == http.StatusTooManyRequests {
+		errorType = UpstreamUnavailable
+	}
+	return &Error{
+		Type:           errorType,
+		Reason:         fmt.Sprintf("%s (upstream status %d)", reason, status),
+		UpstreamStatus: status,
+	}
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return e.Reason
+	}
+	return e.Reason + ": " + e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Handle responds with the status of typed errors and their reason, other errors are internal errors
+func Handle(writer http.ResponseWriter, r *http.Request, err error) {
+	var typed *Error
+	if !stderrors.As(err, &typed) {
+		HandleError(writer, r, err, InternalError)
+		return
+	}
+
+	tracing.Logger(r.Context()).Errorw("Got error while handling event", "error", err, "upstream-status", typed.UpstreamStatus)
+	response := responses[typed.Type]
+	http.Error(writer, response.message+": "+typed.Reason, response.status)
+}
+
+func HandleError(writer http.ResponseWriter, r *http.Request, err error, errorType ErrorType) {
 	if err != nil {
-		logger.Logger.Errorw("Got error while handling event", "error", err)
+		tracing.Logger(r.Context()).Errorw("Got error while handling event", "error", err)
 	}
-	switch errorType {
-	case InternalError:
-		http.Error(writer, "Internal Server Error", http.StatusInternalServerError)
-	case BadInput:
-		http.Error(writer, "Invalid input", http.StatusBadRequest)
-	case UnAuthorized:
-		http.Error(writer, "UnAuthorized", http.StatusUnauthorized)
+	response, ok := responses[errorType]
+	if !ok {
+		response = responses[InternalError]
 	}
+	http.Error(writer, response.message, response.status)
 }
@@ -3,16 +3,39 @@ package events
 import (
 	"github.com/gofrs/uuid"
 	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/config"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/logger"
+	"strconv"
 	"time"
 )
 
+// eventTimeFormat keeps the milliseconds of the Litmos timestamps, so events can be ordered
+const eventTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// createdFormats are tried in order, Litmos sends UTC timestamps without zone
+var createdFormats = []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999"}
+
+// metadataField is the field of the data containing the Litmos metadata
+const metadataField = "litmos"
+
+// eventIDNamespace is the namespace of the event ids derived from Litmos ids
+var eventIDNamespace = uuid.NewV5(uuid.NamespaceURL, "https://github.com/kyma-incubator/connector-tools/litmos-event-gw")
+
 type KymaEvent struct {
-	SourceID         string     `json:"source-id"`
-	EventType        string      `json:"event-type"`
-	EventTypeVersion string      `json:"event-type-version"`
-	EventID          string      `json:"event-id"`
-	EventTime        string      `json:"event-time"`
-	Data             interface{} `json:"data"`
+	SourceID         string          `json:"source-id"`
+	EventType        string          `json:"event-type"`
+	EventTypeVersion string          `json:"event-type-version"`
+	EventID          string          `json:"event-id"`
+	EventTime        string          `json:"event-time"`
+	Data             interface{}     `json:"data"`
+	Litmos           *LitmosMetadata `json:"-"`
+}
+
+// LitmosMetadata identifies the Litmos event the Kyma event was built of. The /v1/events API has no field for it,
+// so it is published as the litmos object of the data
+type LitmosMetadata struct {
+	ID      int32  `json:"id"`
+	Object  string `json:"object"`
+	Created string `json:"created"`
 }
 
 type LitmosEvent struct {
@@ -23,20 +46,67 @@ type LitmosEvent struct {
 	Data    interface{} `json:"data"`
 }
 
-func Map(litmosEvent *LitmosEvent) *KymaEvent {
-	eventId, _ := generateEventID()
-	eventType := config.GlobalConfig.BaseTopic + "." + litmosEvent.Type
-
+// Map builds the Kyma event of the given type and version from the Litmos event
+func Map(litmosEvent *LitmosEvent, eventType string, eventTypeVersion string) *KymaEvent {
+	metadata := &LitmosMetadata{
+		ID:      litmosEvent.ID,
+		Object:  litmosEvent.Object,
+		Created: litmosEvent.Created,
+	}
 	return &KymaEvent{
-		SourceID:         config.GlobalConfig.BaseTopic,
+		SourceID:         config.GlobalConfig.AppName,
 		EventType:        eventType,
-		EventTypeVersion: "v1",
-		EventTime:        time.Now().Format(time.RFC3339),
-		Data:             litmosEvent.Data,
-		EventID:          eventId,
+		EventTypeVersion: eventTypeVersion,
+		EventTime:        eventTime(litmosEvent),
+		Data:             withMetadata(litmosEvent, metadata),
+		EventID:          eventID(litmosEvent),
+		Litmos:           metadata,
 	}
 }
 
+// withMetadata adds the litmos object to the data, data which is not an object or has an own litmos field is
+// published unchanged
+func withMetadata(litmosEvent *LitmosEvent, metadata *LitmosMetadata) interface{} {
+	switch data := litmosEvent.Data.(type) {
+	case nil:
+		return map[string]interface{}{metadataField: metadata}
+	case map[string]interface{}:
+		if _, exists := data[metadataField]; exists {
+			logger.Logger.Warnw("Litmos event data has a litmos field, the Litmos metadata is not published", "litmos-id", litmosEvent.ID)
+			return data
+		}
+		data[metadataField] = metadata
+		return data
+	default:
+		logger.Logger.Warnw("Litmos event data is not an object, the Litmos metadata is not published", "litmos-id", litmosEvent.ID)
+		return data
+	}
+}
+
+// eventID is derived from the app and the Litmos id, so redeliveries of a webhook carry the same event id
+func eventID(litmosEvent *LitmosEvent) string {
+	if litmosEvent.ID == 0 {
+		eventId, _ := generateEventID()
+		logger.Logger.Warnw("Litmos event without id, generated a random event id", "event-type", litmosEvent.Type, "event-id", eventId)
+		return eventId
+	}
+
+	return uuid.NewV5(eventIDNamespace, config.GlobalConfig.AppName+"/"+strconv.FormatInt(int64(litmosEvent.ID), 10)).String()
+}
+
+// eventTime is the creation time of the Litmos event, the time of receiving it if created can't be parsed
+func eventTime(litmosEvent *LitmosEvent) string {
+	for _, format := range createdFormats {
+		if created, err := time.Parse(format, litmosEvent.Created); err == nil {
+			return created.Format(eventTimeFormat)
+		}
+	}
+
+	now := time.Now().Format(eventTimeFormat)
+	logger.Logger.Warnw("Litmos event with invalid created time, using the time of receiving it", "litmos-id", litmosEvent.ID, "created", litmosEvent.Created, "event-time", now)
+	return now
+}
+
 func generateEventID() (string, error) {
 	uid, err := uuid.NewV4()
 	if err != nil {
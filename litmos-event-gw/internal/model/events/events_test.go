@@ -0,0 +1,63 @@
+package events
+
+import (
+	"encoding/json"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/config"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/logger"
+	. "github.com/onsi/gomega"
+	"testing"
+	"time"
+)
+
+func TestMap(t *testing.T) {
+	g := NewGomegaWithT(t)
+	logger.Initialize()
+	config.GlobalConfig = &config.Opts{AppName: "litmos-tenant", BaseTopic: "litmos"}
+
+	le := &LitmosEvent{
+		ID:      4513,
+		Created: "2019-05-06T01:13:19.533",
+		Type:    "achievement.earned",
+		Object:  "event",
+		Data:    map[string]interface{}{"userid": "u1"},
+	}
+
+	ke := Map(le, "achievement.earned", "v2")
+	g.Expect(ke.SourceID).To(Equal("litmos-tenant"))
+	g.Expect(ke.EventType).To(Equal("achievement.earned"))
+	g.Expect(ke.EventTypeVersion).To(Equal("v2"))
+	g.Expect(ke.EventTime).To(Equal("2019-05-06T01:13:19.533Z"))
+	g.Expect(ke.EventID).To(Equal(Map(le, "achievement.earned", "v1").EventID))
+	g.Expect(ke.Litmos).To(Equal(&LitmosMetadata{ID: 4513, Object: "event", Created: "2019-05-06T01:13:19.533"}))
+
+	body, err := json.Marshal(ke)
+	g.Expect(err).Should(BeNil())
+	g.Expect(string(body)).To(ContainSubstring(`"data":{"litmos":{"id":4513,"object":"event","created":"2019-05-06T01:13:19.533"},"userid":"u1"}`))
+
+	other := *le
+	other.ID = 4514
+	g.Expect(Map(&other, "achievement.earned", "v1").EventID).NotTo(Equal(ke.EventID))
+
+	le.Created = "2019-05-06T01:13:19.533+02:00"
+	g.Expect(Map(le, "achievement.earned", "v1").EventTime).To(Equal("2019-05-06T01:13:19.533+02:00"))
+}
+
+func TestMapFallback(t *testing.T) {
+	g := NewGomegaWithT(t)
+	logger.Initialize()
+	config.GlobalConfig = &config.Opts{AppName: "litmos", BaseTopic: "litmos"}
+
+	le := &LitmosEvent{Created: "yesterday", Type: "achievement.earned"}
+
+	ke := Map(le, "achievement.earned", "v1")
+	g.Expect(ke.EventID).NotTo(BeEmpty())
+	g.Expect(ke.EventID).NotTo(Equal(Map(le, "achievement.earned", "v1").EventID))
+
+	eventTime, err := time.Parse(time.RFC3339, ke.EventTime)
+	g.Expect(err).Should(BeNil())
+	g.Expect(eventTime).To(BeTemporally("~", time.Now(), time.Minute))
+	g.Expect(ke.Data).To(HaveKey("litmos"))
+
+	le.Data = []interface{}{"u1"}
+	g.Expect(Map(le, "achievement.earned", "v1").Data).To(Equal([]interface{}{"u1"}))
+}
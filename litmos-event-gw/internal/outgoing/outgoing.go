@@ -2,12 +2,18 @@ package outgoing
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
-	"fmt"
+	stderrors "errors"
 	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/config"
-	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/logger"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/metrics"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/model/errors"
 	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/model/events"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/tracing"
+	"io"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"time"
 )
@@ -19,6 +25,7 @@ type EventForwarder struct {
 
 func NewEventForwarder() *EventForwarder {
 	client := &http.Client{
+		Timeout: config.GlobalConfig.PublishTimeout,
 		Transport: &http.Transport{
 			DisableCompression:  false,
 			MaxIdleConns:        100,
@@ -35,7 +42,15 @@ func NewEventForwarder() *EventForwarder {
 	}
 }
 
-func (e *EventForwarder) Forward(event *events.KymaEvent) (map[string]interface{}, error) {
+// Forward publishes the event with the trace headers of the request and records the outcome in the metrics
+func (e *EventForwarder) Forward(ctx context.Context, event *events.KymaEvent) (map[string]interface{}, error) {
+	start := time.Now()
+	respMap, err := e.forward(ctx, event)
+	metrics.ObserveForward(start, err)
+	return respMap, err
+}
+
+func (e *EventForwarder) forward(ctx context.Context, event *events.KymaEvent) (map[string]interface{}, error) {
 	eventBytes, err := json.Marshal(event)
 	if err != nil {
 		return nil, err
@@ -46,25 +61,36 @@ func (e *EventForwarder) Forward(event *events.KymaEvent) (map[string]interface{
 		return nil, err
 	}
 
+	req.Header.Set("Content-Type", "application/json")
+	tracing.IncludeTraceHeaders(ctx, req.Header)
+
 	resp, err := e.client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, transportError(err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+		tracing.Logger(ctx).Errorw("unexpected response when publishing event", "status", resp.StatusCode, "body", string(body))
+		return nil, errors.Upstream(resp.StatusCode, "unexpected response when publishing event")
+	}
+
 	dec := json.NewDecoder(resp.Body)
 	var respMap map[string]interface{}
 	err = dec.Decode(&respMap)
 	if err != nil {
-		return nil, err
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		errMsg := fmt.Sprintf("unexpected response when publishing event %d (%s)", resp.StatusCode, resp.Status)
-		logger.Logger.Error(errMsg)
-		return respMap, fmt.Errorf(errMsg)
+		return nil, errors.New(errors.UpstreamError, "invalid response when publishing event", err)
 	}
 
 	return respMap, nil
+}
 
+// transportError reports timeouts with 504, all other failures to reach the event bus with 503
+func transportError(err error) error {
+	var netErr net.Error
+	if stderrors.As(err, &netErr) && netErr.Timeout() {
+		return errors.New(errors.UpstreamTimeout, "publishing event timed out", err)
+	}
+	return errors.New(errors.UpstreamUnavailable, "event bus not reachable", err)
 }
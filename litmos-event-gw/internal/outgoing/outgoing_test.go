@@ -0,0 +1,59 @@
+package outgoing
+
+import (
+	"context"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/config"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/logger"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/model/errors"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/model/events"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestForwardErrors(t *testing.T) {
+	g := NewGomegaWithT(t)
+	logger.Initialize()
+
+	tests := []struct {
+		name           string
+		handler        http.HandlerFunc
+		errorType      errors.ErrorType
+		upstreamStatus int
+	}{
+		{"server error", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}, errors.UpstreamError, http.StatusInternalServerError},
+		{"client error", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "invalid event", http.StatusBadRequest)
+		}, errors.UpstreamError, http.StatusBadRequest},
+		{"unavailable", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}, errors.UpstreamUnavailable, http.StatusServiceUnavailable},
+		{"invalid response", func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("OK"))
+		}, errors.UpstreamError, 0},
+		{"timeout", func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		}, errors.UpstreamTimeout, 0},
+	}
+
+	for _, test := range tests {
+		ts := httptest.NewServer(test.handler)
+		config.GlobalConfig = &config.Opts{EventPublishURL: ts.URL, PublishTimeout: 100 * time.Millisecond}
+
+		_, err := NewEventForwarder().Forward(context.Background(), &events.KymaEvent{EventType: "achievement.earned"})
+		ts.Close()
+
+		g.Expect(err).To(BeAssignableToTypeOf(&errors.Error{}), test.name)
+		g.Expect(err.(*errors.Error).Type).To(Equal(test.errorType), test.name)
+		g.Expect(err.(*errors.Error).UpstreamStatus).To(Equal(test.upstreamStatus), test.name)
+	}
+
+	config.GlobalConfig = &config.Opts{EventPublishURL: "http://127.0.0.1:1", PublishTimeout: time.Second}
+	_, err := NewEventForwarder().Forward(context.Background(), &events.KymaEvent{EventType: "achievement.earned"})
+	g.Expect(err).To(BeAssignableToTypeOf(&errors.Error{}))
+	g.Expect(err.(*errors.Error).Type).To(Equal(errors.UpstreamUnavailable))
+}
@@ -0,0 +1,151 @@
+package queue
+
+import (
+	"context"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/metrics"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/model/errors"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/model/events"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/tracing"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Forwarder publishes events to Kyma
+type Forwarder interface {
+	Forward(ctx context.Context, event *events.KymaEvent) (map[string]interface{}, error)
+}
+
+// Enricher adds data to the events before they are published
+type Enricher interface {
+	Enrich(ctx context.Context, event *events.KymaEvent)
+}
+
+type job struct {
+	ctx   context.Context
+	event *events.KymaEvent
+}
+
+// Queue buffers events in memory, a pool of workers publishes them with retries
+type Queue struct {
+	jobs       chan *job
+	forwarder  Forwarder
+	enricher   Enricher
+	maxRetries int
+	backoff    time.Duration
+	workers    sync.WaitGroup
+	lock       sync.RWMutex
+	closed     bool
+}
+
+// New starts the workers, the backoff doubles with every retry. The events are enriched by the workers once
+// before they are published if an enricher is given, so enrichment does not delay the webhook calls
+func New(forwarder Forwarder, enricher Enricher, size int, workers int, maxRetries int, backoff time.Duration) *Queue {
+	q := &Queue{
+		jobs:       make(chan *job, size),
+		forwarder:  forwarder,
+		enricher:   enricher,
+		maxRetries: maxRetries,
+		backoff:    backoff,
+	}
+
+	q.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go q.work()
+	}
+	return q
+}
+
+// Enqueue fails if the queue is full or closed, the caller has to ask for a redelivery.
+// The trace headers of the request are kept, the cancellation of the request is not
+func (q *Queue) Enqueue(ctx context.Context, event *events.KymaEvent) error {
+	q.lock.RLock()
+	defer q.lock.RUnlock()
+
+	if q.closed {
+		return errors.New(errors.UpstreamUnavailable, "event queue is closed", nil)
+	}
+
+	select {
+	case q.jobs <- &job{ctx: tracing.Detach(ctx), event: event}:
+		metrics.QueueDepth.Set(float64(len(q.jobs)))
+		return nil
+	default:
+		metrics.EventsRejected.WithLabelValues(metrics.ReasonQueueFull).Inc()
+		return errors.New(errors.UpstreamUnavailable, "event queue is full", nil)
+	}
+}
+
+// Close stops accepting events and waits until the queued events are published or the context is done
+func (q *Queue) Close(ctx context.Context) error {
+	q.lock.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+	q.lock.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q *Queue) work() {
+	defer q.workers.Done()
+
+	for j := range q.jobs {
+		metrics.QueueDepth.Set(float64(len(q.jobs)))
+		q.publish(j)
+	}
+}
+
+func (q *Queue) publish(j *job) {
+	log := tracing.Logger(j.ctx)
+
+	if q.enricher != nil {
+		q.enricher.Enrich(j.ctx, j.event)
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := q.forwarder.Forward(j.ctx, j.event)
+		if err == nil {
+			log.Infow("Received response for event publishing", "response", resp)
+			return
+		}
+
+		if attempt >= q.maxRetries || !retryable(err) {
+			log.Errorw("dropping event, publishing failed", "event-id", j.event.EventID, "attempts", attempt+1, "error", err)
+			metrics.EventsDropped.WithLabelValues(metrics.ReasonPublishFailed).Inc()
+			return
+		}
+
+		wait := q.backoff << uint(attempt)
+		log.Warnw("publishing event failed, retrying", "event-id", j.event.EventID, "attempt", attempt+1, "retry-in", wait.String(), "error", err)
+		time.Sleep(wait)
+	}
+}
+
+// retryable are failures of the event bus, events rejected by it will be rejected again
+func retryable(err error) bool {
+	typed, ok := err.(*errors.Error)
+	if !ok {
+		return false
+	}
+
+	switch typed.Type {
+	case errors.UpstreamUnavailable, errors.UpstreamTimeout:
+		return true
+	case errors.UpstreamError:
+		return typed.UpstreamStatus >= http.StatusInternalServerError
+	}
+	return false
+}
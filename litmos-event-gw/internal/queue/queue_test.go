@@ -0,0 +1,125 @@
+package queue
+
+import (
+	"context"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/logger"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/metrics"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/model/errors"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/model/events"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+type forwarderMock struct {
+	lock     sync.Mutex
+	attempts map[string]int
+	failures map[string][]error
+	release  chan struct{}
+}
+
+func (f *forwarderMock) Forward(ctx context.Context, event *events.KymaEvent) (map[string]interface{}, error) {
+	if f.release != nil {
+		<-f.release
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	attempt := f.attempts[event.EventID]
+	f.attempts[event.EventID]++
+	if attempt < len(f.failures[event.EventID]) {
+		return nil, f.failures[event.EventID][attempt]
+	}
+	return map[string]interface{}{}, nil
+}
+
+type enricherMock struct {
+	lock     sync.Mutex
+	enriched map[string]int
+}
+
+func (e *enricherMock) Enrich(ctx context.Context, event *events.KymaEvent) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.enriched[event.EventID]++
+	event.Data = "enriched"
+}
+
+func TestRetries(t *testing.T) {
+	g := NewGomegaWithT(t)
+	logger.Initialize()
+
+	forwarder := &forwarderMock{
+		attempts: map[string]int{},
+		failures: map[string][]error{
+			"unavailable": {errors.Upstream(http.StatusServiceUnavailable, "failed"), errors.New(errors.UpstreamTimeout, "timed out", nil)},
+			"rejected":    {errors.Upstream(http.StatusBadRequest, "failed")},
+			"failing": {errors.Upstream(http.StatusInternalServerError, "failed"), errors.Upstream(http.StatusInternalServerError, "failed"),
+				errors.Upstream(http.StatusInternalServerError, "failed")},
+		},
+	}
+	q := New(forwarder, nil, 10, 2, 2, time.Millisecond)
+
+	for _, id := range []string{"unavailable", "rejected", "failing", "ok"} {
+		g.Expect(q.Enqueue(context.Background(), &events.KymaEvent{EventID: id})).Should(BeNil())
+	}
+	g.Expect(q.Close(context.Background())).Should(BeNil())
+
+	g.Expect(forwarder.attempts).To(Equal(map[string]int{"unavailable": 3, "rejected": 1, "failing": 3, "ok": 1}))
+
+	err := q.Enqueue(context.Background(), &events.KymaEvent{EventID: "late"})
+	g.Expect(err.(*errors.Error).Type).To(Equal(errors.UpstreamUnavailable))
+}
+
+func TestQueueFull(t *testing.T) {
+	g := NewGomegaWithT(t)
+	logger.Initialize()
+
+	forwarder := &forwarderMock{attempts: map[string]int{}, release: make(chan struct{})}
+	q := New(forwarder, nil, 1, 1, 0, time.Millisecond)
+
+	g.Expect(q.Enqueue(context.Background(), &events.KymaEvent{EventID: "1"})).Should(BeNil())
+	g.Eventually(func() int { return len(q.jobs) }).Should(Equal(0))
+	g.Expect(q.Enqueue(context.Background(), &events.KymaEvent{EventID: "2"})).Should(BeNil())
+
+	rejected := testutil.ToFloat64(metrics.EventsRejected.WithLabelValues(metrics.ReasonQueueFull))
+	dropped := testutil.ToFloat64(metrics.EventsDropped.WithLabelValues(metrics.ReasonQueueFull))
+	err := q.Enqueue(context.Background(), &events.KymaEvent{EventID: "3"})
+	g.Expect(err).ShouldNot(BeNil())
+	g.Expect(err.(*errors.Error).Type).To(Equal(errors.UpstreamUnavailable))
+	g.Expect(testutil.ToFloat64(metrics.EventsRejected.WithLabelValues(metrics.ReasonQueueFull))).To(Equal(rejected + 1))
+	g.Expect(testutil.ToFloat64(metrics.EventsDropped.WithLabelValues(metrics.ReasonQueueFull))).To(Equal(dropped))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	g.Expect(q.Close(ctx)).To(Equal(context.DeadlineExceeded))
+
+	close(forwarder.release)
+	g.Expect(q.Close(context.Background())).Should(BeNil())
+	g.Expect(forwarder.attempts).To(Equal(map[string]int{"1": 1, "2": 1}))
+}
+
+func TestEnrich(t *testing.T) {
+	g := NewGomegaWithT(t)
+	logger.Initialize()
+
+	forwarder := &forwarderMock{
+		attempts: map[string]int{},
+		failures: map[string][]error{"unavailable": {errors.Upstream(http.StatusServiceUnavailable, "failed")}},
+	}
+	enricher := &enricherMock{enriched: map[string]int{}}
+	q := New(forwarder, enricher, 10, 1, 1, time.Millisecond)
+
+	event := &events.KymaEvent{EventID: "unavailable"}
+	g.Expect(q.Enqueue(context.Background(), event)).Should(BeNil())
+	g.Expect(q.Close(context.Background())).Should(BeNil())
+
+	g.Expect(forwarder.attempts).To(Equal(map[string]int{"unavailable": 2}))
+	g.Expect(enricher.enriched).To(Equal(map[string]int{"unavailable": 1}))
+	g.Expect(event.Data).To(Equal("enriched"))
+}
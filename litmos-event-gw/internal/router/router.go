@@ -1,12 +1,21 @@
 package router
 
 import (
+	"fmt"
 	gh "github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/auth"
 	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/config"
 	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/handlers"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/metrics"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/tracing"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"io"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"strings"
 )
 
 type Rtr struct {
@@ -14,22 +23,69 @@ type Rtr struct {
 	*handlers.EventPublisher
 }
 
-func New() http.Handler {
+func New() (*Rtr, error) {
 	r := mux.NewRouter()
-	ep := handlers.NewEventPublisher()
+	ep, err := handlers.NewEventPublisher()
+	if err != nil {
+		return nil, err
+	}
 
 	r.HandleFunc("/", ep.EventHandler()).Methods(http.MethodPost)
+	r.Use(tracing.Middleware, auth.Middleware(config.GlobalConfig))
 
 	return &Rtr{
-		Handler:        applyLogging(r),
+		Handler:        applyLogging(promhttp.InstrumentHandlerDuration(metrics.RequestDuration, r)),
 		EventPublisher: ep,
-	}
+	}, nil
 }
 
-func applyLogging(r *mux.Router) http.Handler {
+func applyLogging(r http.Handler) http.Handler {
 	if !config.GlobalConfig.LogRequest {
 		return r
+	} else if config.GlobalConfig.SignatureMode == config.SignatureModeQuery && config.GlobalConfig.WebhookSecret != "" {
+		return gh.CustomLoggingHandler(os.Stdout, r, redactedLog(config.GlobalConfig.SecretQueryParam))
 	} else {
 		return gh.LoggingHandler(os.Stdout, r)
 	}
 }
+
+// redactedLog writes the common log format like the default logging handler, with the webhook secret
+// sent as query parameter replaced
+func redactedLog(secretQueryParam string) gh.LogFormatter {
+	return func(writer io.Writer, params gh.LogFormatterParams) {
+		uri := params.Request.RequestURI
+		if uri == "" {
+			uri = params.URL.RequestURI()
+		}
+
+		host, _, err := net.SplitHostPort(params.Request.RemoteAddr)
+		if err != nil {
+			host = params.Request.RemoteAddr
+		}
+		user := "-"
+		if params.URL.User != nil && params.URL.User.Username() != "" {
+			user = params.URL.User.Username()
+		}
+
+		fmt.Fprintf(writer, "%s - %s [%s] \"%s %s %s\" %d %d\n", host, user, params.TimeStamp.Format("02/Jan/2006:15:04:05 -0700"),
+			params.Request.Method, redactQuery(uri, secretQueryParam), params.Request.Proto, params.StatusCode, params.Size)
+	}
+}
+
+// redactQuery replaces the value of the query parameter in the request uri
+func redactQuery(uri string, param string) string {
+	i := strings.Index(uri, "?")
+	if i < 0 {
+		return uri
+	}
+
+	query, err := url.ParseQuery(uri[i+1:])
+	if err != nil {
+		return uri[:i] + "?REDACTED"
+	}
+	if _, ok := query[param]; !ok {
+		return uri
+	}
+	query.Set(param, "REDACTED")
+	return uri[:i+1] + query.Encode()
+}
@@ -0,0 +1,25 @@
+package router
+
+import (
+	. "github.com/onsi/gomega"
+	"testing"
+)
+
+func TestRedactQuery(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	tests := []struct {
+		uri      string
+		expected string
+	}{
+		{"/", "/"},
+		{"/?other=1", "/?other=1"},
+		{"/?secret=s3cr3t", "/?secret=REDACTED"},
+		{"/?other=1&secret=s3cr3t", "/?other=1&secret=REDACTED"},
+		{"/?secret=%zz", "/?REDACTED"},
+	}
+
+	for _, test := range tests {
+		g.Expect(redactQuery(test.uri, "secret")).To(Equal(test.expected), test.uri)
+	}
+}
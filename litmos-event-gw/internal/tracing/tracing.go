@@ -0,0 +1,90 @@
+package tracing
+
+import (
+	"context"
+	"github.com/gofrs/uuid"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/logger"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+const (
+	RequestIDHeader = "X-Request-Id"
+	traceIDHeader   = "X-B3-Traceid"
+	spanIDHeader    = "X-B3-Spanid"
+)
+
+// traceHeaders are propagated to the event bus, see https://istio.io/docs/tasks/telemetry/distributed-tracing/overview/
+var traceHeaders = []string{
+	RequestIDHeader,
+	traceIDHeader,
+	spanIDHeader,
+	"X-B3-Parentspanid",
+	"X-B3-Sampled",
+	"X-B3-Flags",
+	"X-Ot-Span-Context",
+}
+
+type contextKey struct{}
+
+type requestContext struct {
+	headers http.Header
+	logger  *zap.SugaredLogger
+}
+
+// Middleware extracts the trace headers of the request, a request id is generated if the request has none.
+// The request id is returned in the response
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers := http.Header{}
+		for _, header := range traceHeaders {
+			if values, ok := r.Header[http.CanonicalHeaderKey(header)]; ok {
+				headers[http.CanonicalHeaderKey(header)] = values
+			}
+		}
+		if headers.Get(RequestIDHeader) == "" {
+			headers.Set(RequestIDHeader, generateRequestID())
+		}
+		w.Header().Set(RequestIDHeader, headers.Get(RequestIDHeader))
+
+		ctx := &requestContext{
+			headers: headers,
+			logger: logger.Logger.With("request-id", headers.Get(RequestIDHeader),
+				"trace-id", headers.Get(traceIDHeader), "span-id", headers.Get(spanIDHeader)),
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, ctx)))
+	})
+}
+
+// IncludeTraceHeaders adds the trace headers of the request to the headers of an outgoing request
+func IncludeTraceHeaders(ctx context.Context, dst http.Header) {
+	if requestCtx, ok := ctx.Value(contextKey{}).(*requestContext); ok {
+		for header, values := range requestCtx.headers {
+			dst[header] = values
+		}
+	}
+}
+
+// Detach keeps the trace headers and the logger of the request context, but not its cancellation
+func Detach(ctx context.Context) context.Context {
+	if requestCtx, ok := ctx.Value(contextKey{}).(*requestContext); ok {
+		return context.WithValue(context.Background(), contextKey{}, requestCtx)
+	}
+	return context.Background()
+}
+
+// Logger logs with the request id and trace ids of the request, the global logger is used outside of requests
+func Logger(ctx context.Context) *zap.SugaredLogger {
+	if requestCtx, ok := ctx.Value(contextKey{}).(*requestContext); ok {
+		return requestCtx.logger
+	}
+	return logger.Logger
+}
+
+func generateRequestID() string {
+	uid, err := uuid.NewV4()
+	if err != nil {
+		return ""
+	}
+	return uid.String()
+}
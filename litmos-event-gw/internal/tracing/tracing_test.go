@@ -0,0 +1,41 @@
+package tracing
+
+import (
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/logger"
+	. "github.com/onsi/gomega"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	g := NewGomegaWithT(t)
+	logger.Initialize()
+
+	var propagated http.Header
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		propagated = http.Header{}
+		IncludeTraceHeaders(r.Context(), propagated)
+		g.Expect(Logger(r.Context())).NotTo(Equal(logger.Logger))
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("X-Request-Id", "4711")
+	req.Header.Set("X-B3-Traceid", "80f198ee56343ba8")
+	req.Header.Set("X-B3-Sampled", "1")
+	req.Header.Set("Authorization", "Basic secret")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	g.Expect(recorder.Header().Get("X-Request-Id")).To(Equal("4711"))
+	g.Expect(propagated).To(Equal(http.Header{
+		"X-Request-Id": {"4711"},
+		"X-B3-Traceid": {"80f198ee56343ba8"},
+		"X-B3-Sampled": {"1"},
+	}))
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", nil))
+	g.Expect(recorder.Header().Get("X-Request-Id")).NotTo(BeEmpty())
+	g.Expect(propagated.Get("X-Request-Id")).To(Equal(recorder.Header().Get("X-Request-Id")))
+}
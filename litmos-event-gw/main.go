@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/config"
 	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/logger"
+	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/management"
 	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/router"
 	"log"
 	"net/http"
@@ -26,15 +27,30 @@ func startGateway(interrupt <-chan os.Signal) {
 	defer logger.Logger.Sync()
 
 	logger.Logger.Info("starting service...")
-	rtr := router.New()
+	rtr, err := router.New()
+	if err != nil {
+		logger.Logger.Fatalw("invalid event mapping", "error", err)
+	}
 
 	srv := http.Server{
 		Addr:    ":" + "8080",
 		Handler: rtr,
 	}
 
+	managementSrv, err := management.New(config.GlobalConfig)
+	if err != nil {
+		logger.Logger.Fatalw("invalid configuration", "error", err)
+	}
+
+	go func() {
+		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
 	go func() {
-		log.Fatal(srv.ListenAndServe())
+		if err := managementSrv.ListenAndServe(); err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
 	}()
 
 	killSignal := <-interrupt
@@ -49,6 +65,14 @@ func startGateway(interrupt <-chan os.Signal) {
 
 	srv.Shutdown(context.Background())
 
+	drainCtx, cancel := context.WithTimeout(context.Background(), config.GlobalConfig.DrainTimeout)
+	defer cancel()
+	if err := rtr.Shutdown(drainCtx); err != nil {
+		logger.Logger.Errorw("queued events lost on shutdown", "error", err)
+	}
+
+	managementSrv.Shutdown(context.Background())
+
 	logger.Logger.Info("done...")
 
 }
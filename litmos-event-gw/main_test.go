@@ -6,6 +6,7 @@ import (
 	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/config"
 	"github.com/kyma-incubator/connector-tools/litmos-event-gw/internal/model/events"
 	. "github.com/onsi/gomega"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -15,6 +16,8 @@ import (
 
 var httpClient *http.Client = &http.Client{}
 
+var publishedTraceID string
+
 func TestMain(m *testing.M) {
 	ts := setUpEventPublishingService()
 	defer ts.Close()
@@ -40,12 +43,18 @@ func setupGlobalConfig(ts *httptest.Server) {
 		EventPublishURL:    ts.URL,
 		BaseTopic:          "litmos",
 		InSecureSkipVerify: false,
+		UnmappedEvents:     config.UnmappedForward,
+		DefaultVersion:     "v1",
+		PublishTimeout:     time.Second,
+		ManagementPort:     8081,
+		InsecureSkipAuth:   true,
 	}
 }
 
 func setUpEventPublishingService() *httptest.Server {
 	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		println("called...")
+		publishedTraceID = r.Header.Get("X-B3-Traceid")
 		m := make(map[string]string)
 		m["x"] = "y"
 		ba, _ := json.Marshal(m)
@@ -71,3 +80,46 @@ func TestEventIngestionSuccess(t *testing.T) {
 	g.Expect(resp).ShouldNot(BeNil())
 	g.Expect(resp.StatusCode).To(Equal(200))
 }
+
+func TestEventIngestionBadInput(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	req, err := http.NewRequest(http.MethodPost, "http://localhost:8080/", bytes.NewReader([]byte(`{"id":123,`)))
+	resp, err := httpClient.Do(req)
+
+	g.Expect(err).Should(BeNil())
+	g.Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	body, _ := ioutil.ReadAll(resp.Body)
+	g.Expect(string(body)).To(ContainSubstring("body is not a valid Litmos event"))
+}
+
+func TestEventIngestionTracing(t *testing.T) {
+	g := NewGomegaWithT(t)
+	b, _ := json.Marshal(&events.LitmosEvent{ID: 124, Type: "achievement.earned", Object: "event"})
+
+	req, err := http.NewRequest(http.MethodPost, "http://localhost:8080/", bytes.NewReader(b))
+	req.Header.Set("X-B3-Traceid", "80f198ee56343ba864fe8b2a57d3eff7")
+	resp, err := httpClient.Do(req)
+
+	g.Expect(err).Should(BeNil())
+	g.Expect(resp.StatusCode).To(Equal(http.StatusOK))
+	g.Expect(resp.Header.Get("X-Request-Id")).NotTo(BeEmpty())
+	g.Expect(publishedTraceID).To(Equal("80f198ee56343ba864fe8b2a57d3eff7"))
+}
+
+func TestManagementEndpoints(t *testing.T) {
+	g := NewGomegaWithT(t)
+
+	for _, path := range []string{"/healthz", "/ready"} {
+		resp, err := httpClient.Get("http://localhost:8081" + path)
+		g.Expect(err).Should(BeNil())
+		g.Expect(resp.StatusCode).To(Equal(http.StatusOK), path)
+	}
+
+	resp, err := httpClient.Get("http://localhost:8081/metrics")
+	g.Expect(err).Should(BeNil())
+	body, _ := ioutil.ReadAll(resp.Body)
+	g.Expect(string(body)).To(ContainSubstring(`litmos_events_received_total{litmos_type="other"}`))
+	g.Expect(string(body)).To(ContainSubstring(`litmos_events_forwarded_total{result="success"}`))
+	g.Expect(string(body)).To(ContainSubstring("litmos_http_request_duration_seconds_bucket"))
+}
@@ -5,6 +5,8 @@ go 1.12
 require (
 	github.com/prometheus/client_golang v1.0.0
 	github.com/sirupsen/logrus v1.2.0
+	github.com/tidwall/gjson v1.4.0
+	github.com/tidwall/sjson v1.0.4
 	k8s.io/api v0.0.0-20190620084959-7cf5895f2711
 	k8s.io/apimachinery v0.0.0-20190612205821-1799e75a0719
 	k8s.io/client-go v0.0.0-20190620085101-78d2af792bab
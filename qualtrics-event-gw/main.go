@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"github.com/kyma-incubator/connector-tools/qualtrics-event-gw/pkg/event"
+	"github.com/kyma-incubator/connector-tools/qualtrics-event-gw/pkg/expansion"
 	"github.com/kyma-incubator/connector-tools/qualtrics-event-gw/pkg/httphandler"
 	"github.com/kyma-incubator/connector-tools/qualtrics-event-gw/pkg/servicediscovery"
 	"github.com/prometheus/client_golang/prometheus"
@@ -24,6 +25,7 @@ import (
 
 const (
 	responseCodeLabel = "responseCode"
+	resultLabel       = "result"
 )
 
 var (
@@ -48,6 +50,24 @@ var (
 		Help: "The number of requests currently active",
 	})
 
+	topicConfigReloads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "topic_config_reloads_total",
+		Help: "The total number of reloads of the topic mapper configuration",
+	},
+		[]string{resultLabel})
+
+	responseExpansions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "survey_response_expansions_total",
+		Help: "The total number of expansions of survey responses",
+	},
+		[]string{resultLabel})
+
+	topicConfigHash = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "topic_config_info",
+		Help: "The sha256 of the active topic mapper configuration",
+	},
+		[]string{"hash"})
+
 	eventURL *url.URL
 )
 
@@ -147,10 +167,17 @@ func main() {
 	var namespace string
 	var applicationName string
 	var hmacKey string
+	var hmacAdditionalKeys string
+	var hmacConfig hmac.Config
 	var topicConfigLocation string
 	var logLevel string
 	var validateHMAC bool
 	var timeoutMills int64
+	var topicConfigReload time.Duration
+	var qualtricsAPIURL string
+	var qualtricsAPIToken string
+	var expansionTimeoutMills int64
+	var expansionCacheTTL time.Duration
 
 
 	flag.StringVar(&labelSelector, "event-gateway-label-selector", "", "kubernetes label selector "+
@@ -165,14 +192,37 @@ func main() {
 		"Name of the application that sends the events (in Kyma)")
 	flag.StringVar(&hmacKey, "hmac-key", "", "shared key used to validate origin of incoming webhook calls (simple string)")
 	flag.BoolVar(&validateHMAC, "hmac", false, "supplied hmac should be validated")
+	flag.StringVar(&hmacAdditionalKeys, "hmac-additional-keys", "", "comma separated list of further keys "+
+		"accepted besides hmac-key, e.g. while rotating the key")
+	flag.StringVar(&hmacConfig.Algorithm, "hmac-algorithm", hmac.AlgorithmSHA512, "hash algorithm of the hmac (can be sha256, sha512)")
+	flag.StringVar(&hmacConfig.Location, "hmac-location", hmac.LocationForm, "location of the hmac: form (HMAC of the "+
+		"MSG field in a form field) or header (HMAC of the request body in a header)")
+	flag.StringVar(&hmacConfig.Field, "hmac-field", "", "form field or header carrying the hmac (default \"HMAC\" "+
+		"for form, \"X-Qualtrics-Signature\" for header)")
+	flag.StringVar(&hmacConfig.Encoding, "hmac-encoding", hmac.EncodingHex, "encoding of the hmac (can be hex, base64)")
 	flag.StringVar(&topicConfigLocation, "topic-conf", "conf/topic_config.json", "location of the topic mapper configuration file ")
 	flag.StringVar(&logLevel, "log-level", "ERROR", "log level that should be used (can be ERROR, WARN, INFO, DEBUG, TRACE). "+
 		"Trace logs full events and requests ")
+	flag.DurationVar(&topicConfigReload, "topic-conf-reload", 30*time.Second, "interval for checking the topic mapper "+
+		"configuration file for changes, 0 disables reloading")
+	flag.StringVar(&qualtricsAPIURL, "qualtrics-api-url", "", "base url of the Qualtrics API used to expand "+
+		"survey responses, e.g. https://<datacenter>.qualtrics.com (optional, expansion is disabled if empty)")
+	flag.StringVar(&qualtricsAPIToken, "qualtrics-api-token", "", "token of "+
+		"the Qualtrics API (the QUALTRICS_API_TOKEN environment variable is used if empty)")
+	flag.Int64Var(&expansionTimeoutMills, "expansion-timeout", 2000, "timeout for fetching a survey response "+
+		"from the Qualtrics API")
+	flag.DurationVar(&expansionCacheTTL, "expansion-cache-ttl", 10*time.Minute, "time fetched survey responses are "+
+		"cached, 0 disables the cache")
 	flag.Int64Var(&timeoutMills, "timeout", 2000, "timeout for forwarding requests to the event bus")
 
 	flag.Parse()
 	var err error
 
+	//read after parsing, so the token is not printed as default in the usage
+	if qualtricsAPIToken == "" {
+		qualtricsAPIToken = os.Getenv("QUALTRICS_API_TOKEN")
+	}
+
 	//Discover Event Gateway based on Inputs
 	var client *servicediscovery.KubernetesClient
 
@@ -226,13 +276,38 @@ func main() {
 		log.Fatalf("Setup of topic configuration failed with error: %s", err.Error())
 	}
 
-	var handler httphandler.Handler
-	handler = &event.InboundProcessor{
-		SourceID:       applicationName,
-		EventForwarder: &forwarder,
-		TopicMapper:    topicMapper,
+	if topicConfigReload > 0 {
+		go topicMapper.Watch(topicConfigReload, &topicmapper.Metrics{
+			ReloadSuccess: topicConfigReloads.With(prometheus.Labels{resultLabel: "success"}),
+			ReloadFailure: topicConfigReloads.With(prometheus.Labels{resultLabel: "failure"}),
+			ConfigHash:    topicConfigHash,
+		}, nil)
+	}
+
+	inboundProcessor := &event.InboundProcessor{
+		SourceID:        applicationName,
+		EventForwarder:  &forwarder,
+		TopicMapper:     topicMapper,
+		ExpansionMapper: topicMapper,
+	}
+
+	if qualtricsAPIURL != "" {
+		if qualtricsAPIToken == "" {
+			log.Fatalln("Expansion of survey responses is turned on, but no token is supplied. Please supply " +
+				"token (-qualtrics-api-token or QUALTRICS_API_TOKEN)")
+		}
+
+		inboundProcessor.Expander = expansion.NewResponseExpander(qualtricsAPIURL, qualtricsAPIToken,
+			time.Duration(expansionTimeoutMills)*time.Millisecond, expansionCacheTTL, &expansion.Metrics{
+				Fetched: responseExpansions.With(prometheus.Labels{resultLabel: "fetched"}),
+				Cached:  responseExpansions.With(prometheus.Labels{resultLabel: "cached"}),
+				Failed:  responseExpansions.With(prometheus.Labels{resultLabel: "failed"}),
+			})
 	}
 
+	var handler httphandler.Handler
+	handler = inboundProcessor
+
 	// is hmac checking enabled?
 
 	if validateHMAC {
@@ -241,8 +316,19 @@ func main() {
 			log.Fatalln("HMAC validation is turned on, but no key is supplied. Please supply key (-hmac-key)")
 		}
 
+		hmacKeys := []string{hmacKey}
+		for _, additionalKey := range strings.Split(hmacAdditionalKeys, ",") {
+			hmacKeys = append(hmacKeys, strings.TrimSpace(additionalKey))
+		}
+
+		verifier, err := hmac.NewVerifier(hmacKeys, hmacConfig)
+		if err != nil {
+			log.Fatalf("Setup of HMAC validation failed with error: %s", err.Error())
+		}
+
 		handler = &hmac.HMAC{
 			Key:         hmacKey,
+			Verifier:    verifier,
 			NextHandler: handler,
 		}
 	}
@@ -268,7 +354,13 @@ func main() {
 	fmt.Printf("Events are forwarded to: %q\n", internalEventURL)
 	fmt.Printf("Events published in context of application: %q\n", applicationName)
 	fmt.Printf("Validation of HMAC enabled: %t\n", validateHMAC)
+	if validateHMAC {
+		fmt.Printf("HMAC algorithm: %s, location: %s, encoding: %s\n", hmacConfig.Algorithm, hmacConfig.Location,
+			hmacConfig.Encoding)
+	}
 	fmt.Printf("Topic Mapper Configuration Location: %s\n", topicConfigLocation)
+	fmt.Printf("Topic Mapper Configuration Reload Interval (0 is disabled): %s\n", topicConfigReload)
+	fmt.Printf("Qualtrics API for expansion of survey responses (default is empty): %s\n", qualtricsAPIURL)
 	fmt.Printf("Log Level: %s\n", logLevel)
 	fmt.Printf("Request timeout (milliseconds): %d\n", timeoutMills)
 	go management()
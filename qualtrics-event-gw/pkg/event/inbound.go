@@ -17,6 +17,10 @@ type InboundProcessor struct {
 	SourceID       string
 	EventForwarder EventForwarder
 	TopicMapper    TopicMapper
+	//ExpansionMapper and Expander are optional, without them MSG is forwarded as is.
+	//If set, ExpansionMapper maps the topics instead of TopicMapper
+	ExpansionMapper ExpansionMapper
+	Expander        Expander
 }
 
 //HandleRequest takes in a qualtrics event and extracts the needed data
@@ -42,7 +46,7 @@ func (p *InboundProcessor) HandleRequest(r *http.Request, ctx *httphandler.Reque
 		}
 	}
 
-	kymaEventType, kymaEventVersion, err := p.TopicMapper.MapTopic(topic)
+	kymaEventType, kymaEventVersion, mode, err := p.mapTopic(topic)
 
 	if err != nil {
 		log.WithFields(
@@ -65,7 +69,7 @@ func (p *InboundProcessor) HandleRequest(r *http.Request, ctx *httphandler.Reque
 	evt := KymaEvent{
 		EventType:        kymaEventType,
 		EventTypeVersion: kymaEventVersion,
-		Data:             JSONString(dataString),
+		Data:             p.expand(topic, mode, JSONString(dataString), ctx),
 	}
 
 	resp, err := p.EventForwarder.ForwardEvent(&evt, ctx)
@@ -88,3 +92,39 @@ func (p *InboundProcessor) HandleRequest(r *http.Request, ctx *httphandler.Reque
 		Response:     resp,
 	}
 }
+
+//mapTopic maps the topic to the event type and version and the expansion of the survey response
+func (p *InboundProcessor) mapTopic(topic string) (eventName string, eventVersion string, mode string, err error) {
+	if p.ExpansionMapper != nil {
+		return p.ExpansionMapper.MapTopicExpansion(topic)
+	}
+	eventName, eventVersion, err = p.TopicMapper.MapTopic(topic)
+	return eventName, eventVersion, "", err
+}
+
+//expand merges the survey response into the data if configured for the topic.
+//Expansion is best effort, if it fails MSG is forwarded as is
+func (p *InboundProcessor) expand(topic string, mode string, data JSONString,
+	ctx *httphandler.RequestContext) JSONString {
+
+	if mode == "" {
+		return data
+	}
+
+	if p.Expander == nil {
+		log.WithFields(
+			ctx.GetLoggerFields(),
+		).Warnf("Expansion configured for topic %q, but no Qualtrics API configured", topic)
+		return data
+	}
+
+	expanded, err := p.Expander.Expand(data, mode, ctx)
+	if err != nil {
+		log.WithFields(
+			ctx.GetLoggerFields(),
+		).Errorf("Expansion of survey response for topic %q failed, forwarding MSG as is: %s", topic, err.Error())
+		return data
+	}
+
+	return expanded
+}
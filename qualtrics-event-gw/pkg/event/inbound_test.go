@@ -113,3 +113,76 @@ func Test_HandleRequest(t *testing.T) {
 	}
 
 }
+
+type mockExpansionMapper struct{}
+
+func (m *mockExpansionMapper) MapTopicExpansion(qualtricsTopicName string) (eventName string, eventVersion string,
+	expansion string, err error) {
+
+	if qualtricsTopicName == "expanded" || qualtricsTopicName == "ExpanderError" {
+		return qualtricsTopicName, "v1", "embed", nil
+	}
+	return qualtricsTopicName, "v1", "", nil
+}
+
+type mockExpander struct{}
+
+func (m *mockExpander) Expand(data JSONString, mode string, ctx *httphandler.RequestContext) (JSONString, error) {
+	if strings.Contains(string(data), "fail") {
+		return data, fmt.Errorf("expansion failed")
+	}
+	return JSONString(`{"expanded":true}`), nil
+}
+
+type capturingForwarder struct {
+	data JSONString
+}
+
+func (c *capturingForwarder) ForwardEvent(evt *KymaEvent, ctx *httphandler.RequestContext) (map[string]interface{}, error) {
+	c.data = evt.Data
+	return map[string]interface{}{}, nil
+}
+
+type passThroughTopicMapper struct{}
+
+func (m *passThroughTopicMapper) MapTopic(qualtricsTopicName string) (eventName string, eventVersion string, err error) {
+	return qualtricsTopicName, "v1", nil
+}
+
+func Test_HandleRequestExpansion(t *testing.T) {
+
+	forwarder := &capturingForwarder{}
+	processor := InboundProcessor{
+		TopicMapper:     &passThroughTopicMapper{},
+		EventForwarder:  forwarder,
+		ExpansionMapper: &mockExpansionMapper{},
+		Expander:        &mockExpander{},
+	}
+	ctx := &httphandler.RequestContext{TraceHeaders: http.Header{}}
+
+	tests := []struct {
+		topic    string
+		data     string
+		expected string
+	}{
+		{"expanded", `{"ResponseID":"R_1"}`, `{"expanded":true}`},
+		{"expanded", `{"ResponseID":"fail"}`, `{"ResponseID":"fail"}`},
+		{"verbatim", `{"ResponseID":"R_1"}`, `{"ResponseID":"R_1"}`},
+	}
+
+	for _, test := range tests {
+		form := url.Values{}
+		form.Set(topicField, test.topic)
+		form.Set(dataField, test.data)
+
+		req, _ := http.NewRequest(http.MethodPost, "http://www.kyma-project.io", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		if resp := processor.HandleRequest(req, ctx); resp.ResponseCode != 200 {
+			t.Errorf("status code 200 expected, %d received", resp.ResponseCode)
+		}
+		if string(forwarder.data) != test.expected {
+			t.Errorf("topic %s: expected data %s, got %s", test.topic, test.expected, forwarder.data)
+		}
+	}
+}
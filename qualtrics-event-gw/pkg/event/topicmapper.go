@@ -1,5 +1,18 @@
 package event
 
+import "github.com/kyma-incubator/connector-tools/qualtrics-event-gw/pkg/httphandler"
+
 type TopicMapper interface {
 	MapTopic(qualtricsTopicName string) (eventName string, eventVersion string, err error)
 }
+
+//ExpansionMapper maps the topic and returns how the survey response is expanded for it in a single lookup,
+//the expansion is empty if MSG is forwarded as is
+type ExpansionMapper interface {
+	MapTopicExpansion(qualtricsTopicName string) (eventName string, eventVersion string, expansion string, err error)
+}
+
+//Expander fetches the survey response referenced by MSG and merges it into the event data
+type Expander interface {
+	Expand(data JSONString, mode string, ctx *httphandler.RequestContext) (JSONString, error)
+}
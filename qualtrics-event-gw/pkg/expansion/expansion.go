@@ -0,0 +1,204 @@
+package expansion
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/kyma-incubator/connector-tools/qualtrics-event-gw/pkg/event"
+	"github.com/kyma-incubator/connector-tools/qualtrics-event-gw/pkg/httphandler"
+	"github.com/kyma-incubator/connector-tools/qualtrics-event-gw/pkg/topicmapper"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+	"io"
+	"io/ioutil"
+	"k8s.io/apimachinery/pkg/util/cache"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	tokenHeader = "X-API-TOKEN"
+	//responseField - the survey response is embedded in this field of the event data
+	responseField = "Response"
+	//maxCacheEntries - the least recently used survey responses are evicted from a full cache
+	maxCacheEntries = 1000
+)
+
+//Metrics of the expansions, by their outcome
+type Metrics struct {
+	Fetched prometheus.Counter
+	Cached  prometheus.Counter
+	Failed  prometheus.Counter
+}
+
+//msg - the ids of the survey response in the MSG payload of Qualtrics
+type msg struct {
+	SurveyID   string `json:"SurveyID"`
+	ResponseID string `json:"ResponseID"`
+}
+
+//ResponseExpander fetches survey responses from the Qualtrics Responses API
+type ResponseExpander struct {
+	apiURL   string
+	token    string
+	client   *http.Client
+	cacheTTL time.Duration
+	metrics  *Metrics
+	cache    *cache.LRUExpireCache
+}
+
+func NewResponseExpander(apiURL string, token string, timeout time.Duration, cacheTTL time.Duration,
+	metrics *Metrics) *ResponseExpander {
+
+	return &ResponseExpander{
+		apiURL:   strings.TrimSuffix(apiURL, "/"),
+		token:    token,
+		client:   &http.Client{Timeout: timeout},
+		cacheTTL: cacheTTL,
+		metrics:  metrics,
+		cache:    cache.NewLRUExpireCache(maxCacheEntries),
+	}
+}
+
+//Expand embeds the survey response referenced by the data in the "Response" field,
+//or merges its fields into the data. Fields of the data take precedence when flattening.
+//The raw MSG is edited, so the fields of the data keep their order
+func (e *ResponseExpander) Expand(data event.JSONString, mode string,
+	ctx *httphandler.RequestContext) (event.JSONString, error) {
+
+	raw := []byte(data)
+	if !gjson.ValidBytes(raw) || !gjson.ParseBytes(raw).IsObject() {
+		e.metrics.Failed.Inc()
+		return data, fmt.Errorf("MSG is not a JSON object")
+	}
+
+	var ids msg
+	_ = json.Unmarshal(raw, &ids)
+	if ids.SurveyID == "" || ids.ResponseID == "" {
+		e.metrics.Failed.Inc()
+		return data, fmt.Errorf("MSG does not contain SurveyID and ResponseID")
+	}
+
+	response, err := e.response(ids, ctx)
+	if err != nil {
+		e.metrics.Failed.Inc()
+		return data, err
+	}
+
+	expanded := raw
+	if mode == topicmapper.ExpandFlatten {
+		gjson.ParseBytes(response).ForEach(func(key, value gjson.Result) bool {
+			if !gjson.GetBytes(expanded, escapeKey(key.String())).Exists() {
+				expanded, err = setRaw(expanded, key.String(), []byte(value.Raw))
+			}
+			return err == nil
+		})
+	} else {
+		expanded, err = setRaw(expanded, responseField, response)
+	}
+	if err != nil {
+		e.metrics.Failed.Inc()
+		return data, err
+	}
+	return event.JSONString(expanded), nil
+}
+
+//setRaw replaces the field of the object in place, or appends it if it doesn't exist yet
+func setRaw(object []byte, key string, value []byte) ([]byte, error) {
+	if gjson.GetBytes(object, escapeKey(key)).Exists() {
+		return sjson.SetRawBytes(object, escapeKey(key), value)
+	}
+
+	encodedKey, err := json.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+
+	body := bytes.TrimRight(object, " \t\r\n")
+	body = bytes.TrimSpace(body[:len(body)-1])
+	result := make([]byte, 0, len(body)+len(encodedKey)+len(value)+3)
+	result = append(result, body...)
+	if body[len(body)-1] != '{' {
+		result = append(result, ',')
+	}
+	result = append(result, encodedKey...)
+	result = append(result, ':')
+	result = append(result, value...)
+	return append(result, '}'), nil
+}
+
+//escapeKey escapes the characters of a field name which have a meaning in gjson and sjson paths
+func escapeKey(key string) string {
+	var escaped strings.Builder
+	for _, c := range key {
+		if c == '.' || c == '*' || c == '?' || c == '|' || c == '#' || c == '@' || c == '\\' {
+			escaped.WriteRune('\\')
+		}
+		escaped.WriteRune(c)
+	}
+	return escaped.String()
+}
+
+//response returns the raw JSON object of the survey response
+func (e *ResponseExpander) response(ids msg, ctx *httphandler.RequestContext) ([]byte, error) {
+	key := ids.SurveyID + "/" + ids.ResponseID
+
+	if response, ok := e.cache.Get(key); ok {
+		e.metrics.Cached.Inc()
+		return response.([]byte), nil
+	}
+
+	response, err := e.fetch(ids, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	e.metrics.Fetched.Inc()
+	if e.cacheTTL > 0 {
+		e.cache.Add(key, response, e.cacheTTL)
+	}
+	return response, nil
+}
+
+func (e *ResponseExpander) fetch(ids msg, ctx *httphandler.RequestContext) ([]byte, error) {
+	responseURL := fmt.Sprintf("%s/API/v3/surveys/%s/responses/%s", e.apiURL,
+		url.PathEscape(ids.SurveyID), url.PathEscape(ids.ResponseID))
+
+	req, err := http.NewRequest(http.MethodGet, responseURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(tokenHeader, e.token)
+	req.Header.Set("Accept", "application/json")
+
+	log.WithFields(
+		ctx.GetLoggerFields(),
+	).Debugf("Fetching survey response %s of survey %s", ids.ResponseID, ids.SurveyID)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching survey response failed: %s", err.Error())
+	}
+	//noinspection GoUnhandledErrorResult
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("fetching survey response failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid survey response: %s", err.Error())
+	}
+	if !gjson.ParseBytes(result.Result).IsObject() {
+		return nil, fmt.Errorf("invalid survey response: missing result")
+	}
+	return result.Result, nil
+}
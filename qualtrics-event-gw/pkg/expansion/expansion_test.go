@@ -0,0 +1,154 @@
+package expansion
+
+import (
+	"encoding/json"
+	"github.com/kyma-incubator/connector-tools/qualtrics-event-gw/pkg/event"
+	"github.com/kyma-incubator/connector-tools/qualtrics-event-gw/pkg/httphandler"
+	"github.com/kyma-incubator/connector-tools/qualtrics-event-gw/pkg/topicmapper"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const msgInput = `{"Status":"Complete","SurveyID":"SV_22VlHYNeldrrrkp","ResponseID":"R_1l9mcVuXubb4aGm","BrandID":"sapdevelopment"}`
+
+func newMetrics() *Metrics {
+	return &Metrics{
+		Fetched: prometheus.NewCounter(prometheus.CounterOpts{Name: "fetched"}),
+		Cached:  prometheus.NewCounter(prometheus.CounterOpts{Name: "cached"}),
+		Failed:  prometheus.NewCounter(prometheus.CounterOpts{Name: "failed"}),
+	}
+}
+
+func stubAPI(calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+
+		if r.Header.Get("X-API-TOKEN") != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/API/v3/surveys/SV_22VlHYNeldrrrkp/responses/R_1l9mcVuXubb4aGm":
+			_, _ = w.Write([]byte(`{"result":{"responseId":"R_1l9mcVuXubb4aGm","Status":"overridden","values":{"QID1":4}},"meta":{"httpStatus":"200 - OK"}}`))
+		case "/API/v3/surveys/SV_slow/responses/R_slow":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func expand(t *testing.T, expander *ResponseExpander, data string, mode string) (map[string]interface{}, error) {
+	expanded, err := expander.Expand(event.JSONString(data), mode, &httphandler.RequestContext{TraceHeaders: http.Header{}})
+
+	var fields map[string]interface{}
+	if jsonErr := json.Unmarshal([]byte(expanded), &fields); jsonErr != nil {
+		t.Fatalf("expanded data is not a JSON object: %s", jsonErr.Error())
+	}
+	return fields, err
+}
+
+func TestExpand(t *testing.T) {
+
+	var calls int32
+	api := stubAPI(&calls)
+	defer api.Close()
+
+	metrics := newMetrics()
+	expander := NewResponseExpander(api.URL+"/", "token", time.Second, time.Minute, metrics)
+
+	fields, err := expand(t, expander, msgInput, topicmapper.ExpandEmbed)
+	if err != nil {
+		t.Fatalf("expansion failed: %s", err.Error())
+	}
+	response, ok := fields["Response"].(map[string]interface{})
+	if !ok || response["responseId"] != "R_1l9mcVuXubb4aGm" || fields["Status"] != "Complete" {
+		t.Errorf("survey response not embedded: %v", fields)
+	}
+
+	fields, err = expand(t, expander, msgInput, topicmapper.ExpandFlatten)
+	if err != nil {
+		t.Fatalf("expansion failed: %s", err.Error())
+	}
+	if fields["responseId"] != "R_1l9mcVuXubb4aGm" || fields["values"] == nil || fields["Status"] != "Complete" {
+		t.Errorf("survey response not flattened: %v", fields)
+	}
+
+	if calls != 1 {
+		t.Errorf("expected survey response to be fetched once, got %d calls", calls)
+	}
+	if testutil.ToFloat64(metrics.Fetched) != 1 || testutil.ToFloat64(metrics.Cached) != 1 {
+		t.Errorf("expected 1 fetched and 1 cached expansion")
+	}
+}
+
+func TestExpand_FieldOrder(t *testing.T) {
+
+	var calls int32
+	api := stubAPI(&calls)
+	defer api.Close()
+
+	expander := NewResponseExpander(api.URL, "token", time.Second, time.Minute, newMetrics())
+	ctx := &httphandler.RequestContext{TraceHeaders: http.Header{}}
+
+	tests := []struct {
+		data     string
+		mode     string
+		expected string
+	}{
+		{msgInput, topicmapper.ExpandEmbed, `{"Status":"Complete","SurveyID":"SV_22VlHYNeldrrrkp","ResponseID":"R_1l9mcVuXubb4aGm","BrandID":"sapdevelopment",` +
+			`"Response":{"responseId":"R_1l9mcVuXubb4aGm","Status":"overridden","values":{"QID1":4}}}`},
+		{msgInput, topicmapper.ExpandFlatten, `{"Status":"Complete","SurveyID":"SV_22VlHYNeldrrrkp","ResponseID":"R_1l9mcVuXubb4aGm","BrandID":"sapdevelopment",` +
+			`"responseId":"R_1l9mcVuXubb4aGm","values":{"QID1":4}}`},
+		{`{"Response":1,"SurveyID":"SV_22VlHYNeldrrrkp","ResponseID":"R_1l9mcVuXubb4aGm"}`, topicmapper.ExpandEmbed,
+			`{"Response":{"responseId":"R_1l9mcVuXubb4aGm","Status":"overridden","values":{"QID1":4}},"SurveyID":"SV_22VlHYNeldrrrkp","ResponseID":"R_1l9mcVuXubb4aGm"}`},
+	}
+
+	for _, test := range tests {
+		expanded, err := expander.Expand(event.JSONString(test.data), test.mode, ctx)
+		if err != nil {
+			t.Fatalf("expansion failed: %s", err.Error())
+		}
+		if string(expanded) != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.mode, test.expected, expanded)
+		}
+	}
+}
+
+func TestExpand_Failures(t *testing.T) {
+
+	var calls int32
+	api := stubAPI(&calls)
+	defer api.Close()
+
+	metrics := newMetrics()
+	expander := NewResponseExpander(api.URL, "token", 100*time.Millisecond, time.Minute, metrics)
+
+	for _, data := range []string{
+		`{"Status":"Complete"}`,
+		`{"SurveyID":"SV_unknown","ResponseID":"R_unknown"}`,
+		`{"SurveyID":"SV_slow","ResponseID":"R_slow"}`,
+	} {
+		fields, err := expand(t, expander, data, topicmapper.ExpandEmbed)
+		if err == nil {
+			t.Errorf("expected expansion of %s to fail", data)
+		}
+		if _, ok := fields["Response"]; ok {
+			t.Errorf("expected data of failed expansion to be unchanged: %v", fields)
+		}
+	}
+
+	if testutil.ToFloat64(metrics.Failed) != 3 {
+		t.Errorf("expected 3 failed expansions, got %f", testutil.ToFloat64(metrics.Failed))
+	}
+
+	expander = NewResponseExpander(api.URL, "wrong", time.Second, time.Minute, newMetrics())
+	if _, err := expand(t, expander, msgInput, topicmapper.ExpandEmbed); err == nil {
+		t.Error("expected expansion with invalid token to fail")
+	}
+}
@@ -1,13 +1,11 @@
 package hmac
 
 import (
-	"crypto/hmac"
-	"crypto/sha512"
-	"encoding/hex"
 	"fmt"
 	"github.com/kyma-incubator/connector-tools/qualtrics-event-gw/pkg/httphandler"
 	log "github.com/sirupsen/logrus"
 	"net/http"
+	"sync"
 )
 
 const (
@@ -15,58 +13,65 @@ const (
 	hmacField = "HMAC"
 )
 
+//HMAC validates the origin of webhook calls before passing them to the next handler
 type HMAC struct {
+	//Key - used with the form scheme (hex SHA-512 of MSG in the HMAC field) if no Verifier is set
 	Key         string
+	Verifier    SignatureVerifier
 	NextHandler httphandler.Handler
-}
 
-func (h *HMAC) validateHMAC(suppliedHmac string, msg string) (bool, error) {
-	mac := hmac.New(sha512.New, []byte(h.Key))
-	suppliedHmacBytes, err := hex.DecodeString(suppliedHmac)
+	defaultOnce     sync.Once
+	defaultVerifier SignatureVerifier
+	defaultErr      error
+}
 
-	if err != nil {
-		log.Printf("Conversion of HMAC %s to hex byte array failed with error: %s", suppliedHmac, err.Error())
-		return false, err
+//verifier returns the configured verifier, the default verifier for Key is built on the first request only
+func (h *HMAC) verifier() (SignatureVerifier, error) {
+	if h.Verifier != nil {
+		return h.Verifier, nil
 	}
-
-	mac.Write([]byte(msg))
-	expectedMAC := mac.Sum(nil)
-	return hmac.Equal(suppliedHmacBytes, expectedMAC), nil
+	h.defaultOnce.Do(func() {
+		h.defaultVerifier, h.defaultErr = NewVerifier([]string{h.Key}, Config{})
+	})
+	return h.defaultVerifier, h.defaultErr
 }
 
 func (h *HMAC) HandleRequest(r *http.Request, ctx *httphandler.RequestContext) *httphandler.Response {
-	r.ParseForm()
-	msg := r.Form.Get(dataField)
-	suppliedHmac := r.Form.Get(hmacField)
-	validationResult, err := h.validateHMAC(suppliedHmac, msg)
+	verifier, err := h.verifier()
 	if err != nil {
-
 		log.WithFields(
 			ctx.GetLoggerFields(),
-		).Error("validation of hmac failed: ", err.Error())
+		).Error("hmac verifier setup failed: ", err.Error())
 
 		return &httphandler.Response{
-			ResponseCode: 400,
+			ResponseCode: 500,
 			IsSuccess:    false,
 			Response: httphandler.JsonError{
-				Message: fmt.Sprint("validation of hmac failed: ", err.Error()),
+				Message: "validation of hmac failed",
 			},
 		}
 	}
 
-	if validationResult {
+	err = verifier.Verify(r)
+	if err == nil {
 		return h.NextHandler.HandleRequest(r, ctx)
-	} else {
-		log.WithFields(
-			ctx.GetLoggerFields(),
-		).Error("validation of hmac failed, either message is not authentic or key is not aligned")
+	}
 
-		return &httphandler.Response{
-			ResponseCode: 403,
-			IsSuccess:    false,
-			Response: httphandler.JsonError{
-				Message: fmt.Sprint("validation of hmac failed, either message is not authentic or key is not aligned"),
-			},
-		}
+	log.WithFields(
+		ctx.GetLoggerFields(),
+	).Error("validation of hmac failed: ", err.Error())
+
+	//a missing signature is an unauthenticated call, everything else is not authentic
+	responseCode := 403
+	if err == ErrMissingSignature {
+		responseCode = 401
+	}
+
+	return &httphandler.Response{
+		ResponseCode: responseCode,
+		IsSuccess:    false,
+		Response: httphandler.JsonError{
+			Message: fmt.Sprint("validation of hmac failed: ", err.Error()),
+		},
 	}
 }
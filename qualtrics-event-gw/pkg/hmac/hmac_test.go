@@ -1,6 +1,18 @@
 package hmac
 
-import "testing"
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/kyma-incubator/connector-tools/qualtrics-event-gw/pkg/httphandler"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
 
 const (
 	messageInput     = "{\"Status\":\"Complete\",\"SurveyID\":\"SV_1AmkKSDmoZ4XlQx\",\"ResponseID\":\"R_0B7IcnXszjEUHLP\",\"CompletedDate\":\"2019-06-25 07:58:25\",\"BrandID\":\"sapdevelopment\"}"
@@ -8,28 +20,145 @@ const (
 	key              = "kyma4ever"
 )
 
-func Test_validateHMAC(t *testing.T) {
+func TestVerifier_verify(t *testing.T) {
 
-	subject := HMAC{Key: key}
+	subject, err := NewVerifier([]string{key}, Config{})
+	if err != nil {
+		t.Fatalf("creating default verifier failed: %s", err.Error())
+	}
 
-	result, _ := subject.validateHMAC(hmacTargetResult, messageInput)
+	if err := subject.verify(hmacTargetResult, []byte(messageInput)); err != nil {
+		t.Errorf("HMAC validation failed, hmac should be equal but is different: %s", err.Error())
+	}
 
-	if result == false {
-		t.Errorf("HMAC validation failed, hmac should be equal but is different")
+	if err := subject.verify(hmacTargetResult, []byte(messageInput+"abc")); err != ErrInvalidSignature {
+		t.Errorf("HMAC validation failed, hmac should be different but is equal")
 	}
 
-	result, _ = subject.validateHMAC(hmacTargetResult, messageInput+"abc")
+	subject2, _ := NewVerifier([]string{key + "x"}, Config{})
 
-	if result == true {
+	if err := subject2.verify(hmacTargetResult, []byte(messageInput)); err != ErrInvalidSignature {
 		t.Errorf("HMAC validation failed, hmac should be different but is equal")
 	}
 
-	subject2 := HMAC{Key: key + "x"}
+	if err := subject.verify("not hex", []byte(messageInput)); err == nil || err == ErrInvalidSignature {
+		t.Errorf("HMAC validation failed, malformed hmac not detected: %v", err)
+	}
 
-	result, _ = subject2.validateHMAC(hmacTargetResult, messageInput)
+}
 
-	if result == true {
-		t.Errorf("HMAC validation failed, hmac should be different but is equal")
+func sign(newHash func() hash.Hash, key string, msg string) []byte {
+	mac := hmac.New(newHash, []byte(key))
+	mac.Write([]byte(msg))
+	return mac.Sum(nil)
+}
+
+func formRequest(field string, signature string) *http.Request {
+	form := url.Values{dataField: {messageInput}, "Topic": {"sapdevelopment.controlpanel.activateSurvey"}}
+	if signature != "" {
+		form.Set(field, signature)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+func TestVerifier_Form(t *testing.T) {
+
+	verifier, err := NewVerifier([]string{key}, Config{})
+	if err != nil {
+		t.Fatalf("creating default verifier failed: %s", err.Error())
+	}
+
+	if err := verifier.Verify(formRequest(hmacField, hmacTargetResult)); err != nil {
+		t.Errorf("valid form hmac rejected: %s", err.Error())
+	}
+	if err := verifier.Verify(formRequest(hmacField, "")); err != ErrMissingSignature {
+		t.Errorf("missing form hmac not detected: %v", err)
+	}
+
+	verifier, _ = NewVerifier([]string{key}, Config{Algorithm: AlgorithmSHA256, Field: "Signature", Encoding: EncodingBase64})
+	signature := base64.StdEncoding.EncodeToString(sign(sha256.New, key, messageInput))
+	if err := verifier.Verify(formRequest("Signature", signature)); err != nil {
+		t.Errorf("valid base64 sha256 form hmac rejected: %s", err.Error())
+	}
+	if err := verifier.Verify(formRequest("Signature", hex.EncodeToString(sign(sha256.New, key, messageInput)))); err == nil {
+		t.Error("hex hmac accepted by base64 verifier")
+	}
+}
+
+func TestVerifier_Header(t *testing.T) {
+
+	verifier, err := NewVerifier([]string{"new-key", key}, Config{Algorithm: AlgorithmSHA256, Location: LocationHeader})
+	if err != nil {
+		t.Fatalf("creating header verifier failed: %s", err.Error())
+	}
+
+	body := url.Values{dataField: {messageInput}}.Encode()
+	for _, signature := range []string{
+		hex.EncodeToString(sign(sha256.New, key, body)),
+		"sha256=" + hex.EncodeToString(sign(sha256.New, "new-key", body)),
+	} {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.Header.Set(DefaultHeader, signature)
+
+		if err := verifier.Verify(r); err != nil {
+			t.Errorf("valid header hmac %s rejected: %s", signature, err.Error())
+		}
+		if r.FormValue(dataField) != messageInput {
+			t.Error("request body not readable after verification")
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set(DefaultHeader, hex.EncodeToString(sign(sha256.New, "old-key", body)))
+	if err := verifier.Verify(r); err != ErrInvalidSignature {
+		t.Errorf("hmac of unknown key not rejected: %v", err)
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	if err := verifier.Verify(r); err != ErrMissingSignature {
+		t.Errorf("missing header hmac not detected: %v", err)
+	}
+}
+
+func TestNewVerifier_Invalid(t *testing.T) {
+
+	for _, config := range []Config{{Algorithm: "md5"}, {Location: "query"}, {Encoding: "base32"}} {
+		if _, err := NewVerifier([]string{key}, config); err == nil {
+			t.Errorf("invalid config %+v accepted", config)
+		}
+	}
+	if _, err := NewVerifier([]string{""}, Config{}); err == nil {
+		t.Error("verifier without key accepted")
 	}
+}
+
+type nextHandler struct{}
+
+func (n *nextHandler) HandleRequest(r *http.Request, ctx *httphandler.RequestContext) *httphandler.Response {
+	return &httphandler.Response{ResponseCode: 200, IsSuccess: true}
+}
 
+func TestHMAC_HandleRequest(t *testing.T) {
+
+	subject := HMAC{Key: key, NextHandler: &nextHandler{}}
+	ctx := &httphandler.RequestContext{TraceHeaders: http.Header{}}
+
+	tests := []struct {
+		signature    string
+		responseCode int
+	}{
+		{hmacTargetResult, 200},
+		{"", 401},
+		{hex.EncodeToString(sign(sha256.New, key, messageInput)), 403},
+		{"not-hex", 403},
+	}
+
+	for _, test := range tests {
+		if resp := subject.HandleRequest(formRequest(hmacField, test.signature), ctx); resp.ResponseCode != test.responseCode {
+			t.Errorf("hmac %q: expected response code %d, got %d", test.signature, test.responseCode, resp.ResponseCode)
+		}
+	}
 }
@@ -0,0 +1,167 @@
+package hmac
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+const (
+	AlgorithmSHA256 = "sha256"
+	AlgorithmSHA512 = "sha512"
+
+	LocationForm   = "form"
+	LocationHeader = "header"
+
+	EncodingHex    = "hex"
+	EncodingBase64 = "base64"
+
+	//DefaultHeader carries the signature of the body in the header scheme
+	DefaultHeader = "X-Qualtrics-Signature"
+)
+
+var (
+	//ErrMissingSignature - the request carries no signature at the configured location
+	ErrMissingSignature = errors.New("missing hmac signature")
+	//ErrInvalidSignature - the signature does not match any of the active keys
+	ErrInvalidSignature = errors.New("either message is not authentic or key is not aligned")
+)
+
+//SignatureVerifier checks the origin of webhook calls
+type SignatureVerifier interface {
+	Verify(r *http.Request) error
+}
+
+//Config of the verifier, empty values are the defaults of the form scheme
+type Config struct {
+	//Algorithm - sha256 or sha512 (default)
+	Algorithm string
+	//Location - form (default) signs the MSG field, header signs the raw body
+	Location string
+	//Field - form field or header carrying the signature, HMAC or X-Qualtrics-Signature by default
+	Field string
+	//Encoding - hex (default) or base64
+	Encoding string
+}
+
+//Verifier accepts signatures made with any of its keys, so keys can be rotated without dropping events
+type Verifier struct {
+	keys     [][]byte
+	newHash  func() hash.Hash
+	location string
+	field    string
+	encoding string
+}
+
+//NewVerifier validates the config and applies the defaults
+func NewVerifier(keys []string, config Config) (*Verifier, error) {
+	verifier := &Verifier{}
+
+	for _, key := range keys {
+		if key != "" {
+			verifier.keys = append(verifier.keys, []byte(key))
+		}
+	}
+	if len(verifier.keys) == 0 {
+		return nil, fmt.Errorf("no hmac key supplied")
+	}
+
+	switch strings.ToLower(config.Algorithm) {
+	case "", AlgorithmSHA512:
+		verifier.newHash = sha512.New
+	case AlgorithmSHA256:
+		verifier.newHash = sha256.New
+	default:
+		return nil, fmt.Errorf("unknown hmac algorithm %q", config.Algorithm)
+	}
+
+	switch strings.ToLower(config.Location) {
+	case "", LocationForm:
+		verifier.location, verifier.field = LocationForm, hmacField
+	case LocationHeader:
+		verifier.location, verifier.field = LocationHeader, DefaultHeader
+	default:
+		return nil, fmt.Errorf("unknown hmac location %q", config.Location)
+	}
+	if config.Field != "" {
+		verifier.field = config.Field
+	}
+
+	switch strings.ToLower(config.Encoding) {
+	case "", EncodingHex:
+		verifier.encoding = EncodingHex
+	case EncodingBase64:
+		verifier.encoding = EncodingBase64
+	default:
+		return nil, fmt.Errorf("unknown hmac encoding %q", config.Encoding)
+	}
+
+	return verifier, nil
+}
+
+//Verify reads the signature and the signed message from the request, the body stays readable for the next handler
+func (v *Verifier) Verify(r *http.Request) error {
+	var signature string
+	var msg []byte
+
+	if v.location == LocationHeader {
+		signature = r.Header.Get(v.field)
+		if signature == "" {
+			return ErrMissingSignature
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			return fmt.Errorf("reading request body failed: %s", err.Error())
+		}
+		_ = r.Body.Close()
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		msg = body
+	} else {
+		_ = r.ParseForm()
+		signature = r.Form.Get(v.field)
+		if signature == "" {
+			return ErrMissingSignature
+		}
+		msg = []byte(r.Form.Get(dataField))
+	}
+
+	return v.verify(signature, msg)
+}
+
+func (v *Verifier) verify(signature string, msg []byte) error {
+	signature = strings.TrimSpace(signature)
+	//optional algorithm prefix, e.g. sha256=<signature>
+	for _, prefix := range []string{AlgorithmSHA256 + "=", AlgorithmSHA512 + "="} {
+		signature = strings.TrimPrefix(signature, prefix)
+	}
+
+	var supplied []byte
+	var err error
+	if v.encoding == EncodingBase64 {
+		supplied, err = base64.StdEncoding.DecodeString(signature)
+	} else {
+		supplied, err = hex.DecodeString(signature)
+	}
+	if err != nil {
+		return fmt.Errorf("signature is not %s encoded: %s", v.encoding, err.Error())
+	}
+
+	for _, key := range v.keys {
+		mac := hmac.New(v.newHash, key)
+		mac.Write(msg)
+		if hmac.Equal(supplied, mac.Sum(nil)) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
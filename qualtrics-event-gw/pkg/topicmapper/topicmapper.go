@@ -1,42 +1,94 @@
 package topicmapper
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
 	"io/ioutil"
 	"os"
 	"regexp"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type mapperConfig struct {
 	QualtricsTopicRegex string `json:"qualtricsTopicRegex"`
 	KymaEventName       string `json:"kymaEventName"`
 	KymaEventVersion    string `json:"kymaEventVersion"`
+	ExpandResponse      string `json:"expandResponse"`
 }
 
 type configCache struct {
 	Regex            *regexp.Regexp
 	KymaEventName    string
 	KymaEventVersion string
+	ExpandResponse   string
 }
 
+const (
+	//ExpandEmbed - the survey response is embedded in the event data
+	ExpandEmbed = "embed"
+	//ExpandFlatten - the fields of the survey response are merged into the event data
+	ExpandFlatten = "flatten"
+)
+
 type Mapper struct {
-	cache []configCache
+	file string
+	//holds the []configCache of the last valid config, swapped on reload
+	cache atomic.Value
+	//serializes reloads, MapTopic does not need it
+	reloadLock sync.Mutex
+	hash       string
+	//hash of the last invalid config, it is rejected only once
+	rejectedHash string
+}
+
+//Metrics of the reloads of the topic mapper configuration
+type Metrics struct {
+	ReloadSuccess prometheus.Counter
+	ReloadFailure prometheus.Counter
+	//ConfigHash is 1 for the hash of the active configuration
+	ConfigHash *prometheus.GaugeVec
 }
 
 func New(file string) (*Mapper, error) {
+	configData, hash, err := readConfig(file)
+	if err != nil {
+		return nil, err
+	}
+
+	cache, err := parseConfig(configData)
+	if err != nil {
+		return nil, err
+	}
+
+	mapper := &Mapper{file: file, hash: hash}
+	mapper.cache.Store(cache)
+	return mapper, nil
+}
+
+func readConfig(file string) ([]byte, string, error) {
 	configFile, err := os.Open(file)
 	if err != nil {
-		return nil, fmt.Errorf("error opening topic mapper config: %s", err.Error())
+		return nil, "", fmt.Errorf("error opening topic mapper config: %s", err.Error())
 	}
 	//noinspection GoUnhandledErrorResult
 	defer configFile.Close()
 
 	configData, err := ioutil.ReadAll(configFile)
 	if err != nil {
-		return nil, fmt.Errorf("error reading topic mapper config: %s", err.Error())
+		return nil, "", fmt.Errorf("error reading topic mapper config: %s", err.Error())
 	}
 
+	sum := sha256.Sum256(configData)
+	return configData, hex.EncodeToString(sum[:]), nil
+}
+
+func parseConfig(configData []byte) ([]configCache, error) {
 	var mapperConfigList []mapperConfig
 
 	if err := json.Unmarshal(configData, &mapperConfigList); err != nil {
@@ -53,24 +105,117 @@ func New(file string) (*Mapper, error) {
 			return nil, fmt.Errorf("error parsing \"qualtricsTopicRegex\": %q at config item %d: %s",
 				currentConfig.QualtricsTopicRegex, i, err.Error())
 		}
+
+		switch currentConfig.ExpandResponse {
+		case "", ExpandEmbed, ExpandFlatten:
+		default:
+			return nil, fmt.Errorf("error parsing \"expandResponse\": %q at config item %d: must be %q or %q",
+				currentConfig.ExpandResponse, i, ExpandEmbed, ExpandFlatten)
+		}
+
 		result[i] = configCache{
 			Regex:            regex,
 			KymaEventName:    currentConfig.KymaEventName,
 			KymaEventVersion: currentConfig.KymaEventVersion,
+			ExpandResponse:   currentConfig.ExpandResponse,
 		}
 	}
 
-	return &Mapper{cache: result}, nil
+	return result, nil
+}
 
+//Hash returns the sha256 of the active configuration
+func (m *Mapper) Hash() string {
+	m.reloadLock.Lock()
+	defer m.reloadLock.Unlock()
+
+	return m.hash
+}
+
+//Reload reads the configuration file again and swaps the mapping if the content changed.
+//An invalid configuration is rejected and the active one is kept
+func (m *Mapper) Reload() (changed bool, err error) {
+	m.reloadLock.Lock()
+	defer m.reloadLock.Unlock()
+
+	configData, hash, err := readConfig(m.file)
+	if err != nil {
+		return false, err
+	}
+
+	if hash == m.hash || hash == m.rejectedHash {
+		return false, nil
+	}
+
+	cache, err := parseConfig(configData)
+	if err != nil {
+		m.rejectedHash = hash
+		return false, err
+	}
+
+	m.cache.Store(cache)
+	m.hash = hash
+	return true, nil
+}
+
+//Watch polls the configuration file and reloads it on changes until stop is closed.
+//Polling also detects the symlink swaps of mounted ConfigMaps
+func (m *Mapper) Watch(interval time.Duration, metrics *Metrics, stop <-chan struct{}) {
+	activeHash := m.Hash()
+	metrics.ConfigHash.WithLabelValues(activeHash).Set(1)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		changed, err := m.Reload()
+		if err != nil {
+			metrics.ReloadFailure.Inc()
+			log.WithFields(log.Fields{
+				"file":       m.file,
+				"activeHash": activeHash,
+			}).Errorf("Reload of topic mapper configuration failed, keeping active configuration: %s", err.Error())
+			continue
+		}
+		if !changed {
+			continue
+		}
+
+		metrics.ReloadSuccess.Inc()
+		metrics.ConfigHash.DeleteLabelValues(activeHash)
+		log.WithFields(log.Fields{
+			"file":         m.file,
+			"previousHash": activeHash,
+			"hash":         m.Hash(),
+		}).Info("Topic mapper configuration reloaded")
+
+		activeHash = m.Hash()
+		metrics.ConfigHash.WithLabelValues(activeHash).Set(1)
+	}
 }
 
 func (m *Mapper) MapTopic(qualtricsTopicName string) (eventName string, eventVersion string, err error) {
-	for _, cacheItem := range m.cache {
+	eventName, eventVersion, _, err = m.MapTopicExpansion(qualtricsTopicName)
+	return eventName, eventVersion, err
+}
+
+//MapTopicExpansion maps the topic like MapTopic and also returns the expansion of the survey response
+//configured for it, empty if there is none. Both are taken from the same configuration, even during a reload
+func (m *Mapper) MapTopicExpansion(qualtricsTopicName string) (eventName string, eventVersion string,
+	expansion string, err error) {
+
+	for _, cacheItem := range m.cache.Load().([]configCache) {
 
 		if cacheItem.Regex.MatchString(qualtricsTopicName) {
-			return cacheItem.KymaEventName, cacheItem.KymaEventVersion, nil
+			return cacheItem.KymaEventName, cacheItem.KymaEventVersion, cacheItem.ExpandResponse, nil
 		}
 	}
 
-	return eventName, eventVersion, fmt.Errorf("no matching event Type found for topic %q", qualtricsTopicName)
+	return eventName, eventVersion, expansion, fmt.Errorf("no matching event Type found for topic %q", qualtricsTopicName)
 }
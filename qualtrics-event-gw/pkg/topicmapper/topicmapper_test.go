@@ -1,6 +1,14 @@
 package topicmapper
 
-import "testing"
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
 
 func TestNew(t *testing.T) {
 
@@ -51,3 +59,126 @@ func TestMapTopic(t *testing.T) {
 	}
 
 }
+
+func TestReload(t *testing.T) {
+
+	dir, _ := ioutil.TempDir("", "topicmapper")
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "topic_config.json")
+	write := func(content string) {
+		if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+			t.Fatalf("writing config failed: %s", err.Error())
+		}
+	}
+
+	write(`[{"qualtricsTopicRegex": "^\\w+\\.controlpanel\\.activateSurvey$", "kymaEventName": "controlpanel.activateSurvey", "kymaEventVersion": "v1"}]`)
+	mapper, err := New(file)
+	if err != nil {
+		t.Fatalf("Reading valid config failed: %s", err.Error())
+	}
+	initialHash := mapper.Hash()
+
+	if changed, err := mapper.Reload(); changed || err != nil {
+		t.Errorf("Unchanged config reloaded: %t %v", changed, err)
+	}
+
+	write(`[{"qualtricsTopicRegex": "^\\w+\\.controlpanel\\.activateSurvey$", "kymaEventName": "controlpanel.surveyActivated", "kymaEventVersion": "v2"}]`)
+	if changed, err := mapper.Reload(); !changed || err != nil {
+		t.Errorf("Changed config not reloaded: %t %v", changed, err)
+	}
+	eventName, eventVersion, err := mapper.MapTopic("sapdevelopment.controlpanel.activateSurvey")
+	if err != nil || eventName != "controlpanel.surveyActivated" || eventVersion != "v2" {
+		t.Error("Topic not mapped with reloaded config")
+	}
+	if mapper.Hash() == initialHash {
+		t.Error("Hash not updated on reload")
+	}
+
+	write(`[{"qualtricsTopicRegex": "(", "kymaEventName": "broken", "kymaEventVersion": "v1"}]`)
+	if _, err := mapper.Reload(); err == nil {
+		t.Error("Invalid config not rejected")
+	}
+	if changed, err := mapper.Reload(); changed || err != nil {
+		t.Errorf("Invalid config rejected more than once: %t %v", changed, err)
+	}
+	eventName, _, err = mapper.MapTopic("sapdevelopment.controlpanel.activateSurvey")
+	if err != nil || eventName != "controlpanel.surveyActivated" {
+		t.Error("Active config not kept after invalid reload")
+	}
+}
+
+func TestWatch(t *testing.T) {
+
+	dir, _ := ioutil.TempDir("", "topicmapper")
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "topic_config.json")
+	_ = ioutil.WriteFile(file, []byte(`[]`), 0600)
+	mapper, _ := New(file)
+
+	metrics := &Metrics{
+		ReloadSuccess: prometheus.NewCounter(prometheus.CounterOpts{Name: "success"}),
+		ReloadFailure: prometheus.NewCounter(prometheus.CounterOpts{Name: "failure"}),
+		ConfigHash:    prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "hash"}, []string{"hash"}),
+	}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		mapper.Watch(5*time.Millisecond, metrics, stop)
+		close(done)
+	}()
+
+	_ = ioutil.WriteFile(file, []byte(`[{"qualtricsTopicRegex": "^a$", "kymaEventName": "a", "kymaEventVersion": "v1"}]`), 0600)
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, _, err := mapper.MapTopic("a"); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Changed config not picked up by watch")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	close(stop)
+	<-done
+
+	if value := testutil.ToFloat64(metrics.ReloadSuccess); value != 1 {
+		t.Errorf("Expected 1 successful reload, got %f", value)
+	}
+	if value := testutil.ToFloat64(metrics.ConfigHash.WithLabelValues(mapper.Hash())); value != 1 {
+		t.Errorf("Expected hash of active config, got %f", value)
+	}
+}
+
+func TestExpansion(t *testing.T) {
+
+	dir, _ := ioutil.TempDir("", "topicmapper")
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "topic_config.json")
+	_ = ioutil.WriteFile(file, []byte(`[
+		{"qualtricsTopicRegex": "completedResponse", "kymaEventName": "a", "kymaEventVersion": "v1", "expandResponse": "flatten"},
+		{"qualtricsTopicRegex": ".*", "kymaEventName": "b", "kymaEventVersion": "v1"}
+	]`), 0600)
+
+	mapper, err := New(file)
+	if err != nil {
+		t.Fatalf("Reading valid config failed: %s", err.Error())
+	}
+	eventName, _, expansion, err := mapper.MapTopicExpansion("sapdevelopment.surveyengine.completedResponse.SV_1")
+	if err != nil || eventName != "a" || expansion != ExpandFlatten {
+		t.Errorf("Expected event a with expansion %q, got %q with %q (%v)", ExpandFlatten, eventName, expansion, err)
+	}
+	eventName, _, expansion, err = mapper.MapTopicExpansion("sapdevelopment.controlpanel.activateSurvey")
+	if err != nil || eventName != "b" || expansion != "" {
+		t.Errorf("Expected event b without expansion, got %q with %q (%v)", eventName, expansion, err)
+	}
+
+	_ = ioutil.WriteFile(file, []byte(`[{"qualtricsTopicRegex": ".*", "kymaEventName": "a", "kymaEventVersion": "v1", "expandResponse": "inline"}]`), 0600)
+	if _, err := New(file); err == nil {
+		t.Error("Invalid expansion accepted")
+	}
+}